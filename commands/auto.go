@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"github.com/fatih/color"
 	"log"
@@ -24,45 +25,91 @@ func dotenvToDuration(key string) time.Duration {
 	return duration
 }
 
-func startNewCycle(wg *sync.WaitGroup, lock chan struct{}) {
+func startNewCycle(ctx context.Context, wg *sync.WaitGroup, lock chan struct{}) {
 	defer wg.Done()
 	duration := dotenvToDuration("AUTO_INTERVAL_NEW")
 	color.Magenta("Starting new cycle every %s", duration.String())
-	for range time.Tick(duration) {
+
+	ticker := time.NewTicker(duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
 		lock <- struct{}{} // acquire
 		fmt.Println(time.Now().Format(time.RubyDate))
-		err := New()
-		if err != nil {
-			log.Fatal(err)
+		err := New(ctx)
+		if err != nil && ctx.Err() == nil {
+			handleAutoError(err)
 		}
 		<-lock // release
 	}
 }
 
-func updateRunningCycles(wg *sync.WaitGroup, lock chan struct{}) {
+// pollsWithStream is true when cycles change without a fill of their
+// orders, on the price or with time, so they are updated even while the
+// user stream is up
+func pollsWithStream() bool {
+	stop, _ := getStopLoss()
+	retrace, _ := getTrailingTakeProfit()
+	distance, _ := getBuyMaxDistance()
+	return stop > 0 || retrace > 0 || distance > 0 || getBuyMaxAge() > 0
+}
+
+// updateRunningCycles polls the orders, only while the user stream, if any,
+// may have missed fills, unless pollsWithStream
+func updateRunningCycles(ctx context.Context, wg *sync.WaitGroup, lock chan struct{}, stream *userStream) {
 	defer wg.Done()
 	duration := dotenvToDuration("AUTO_INTERVAL_UPDATE")
 	color.Magenta("Updating running cycles every %s", duration.String())
-	for range time.Tick(duration) {
+
+	ticker := time.NewTicker(duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if stream != nil && !stream.needsPolling() && !pollsWithStream() {
+			continue
+		}
+
 		lock <- struct{}{} // acquire
 		fmt.Println(time.Now().Format(time.RubyDate))
-		err := Update()
-		if err != nil {
-			log.Fatal(err)
+		err := Update(ctx)
+		if err != nil && ctx.Err() == nil {
+			handleAutoError(err)
+		} else if err == nil && stream != nil {
+			stream.polled()
 		}
 		<-lock // release
 	}
 }
 
-func Auto() {
+// Auto runs until ctx is done, then cancels the calls in flight and waits
+// for the runs to stop
+func Auto(ctx context.Context) {
 	color.Yellow("Starting Auto Mode - CTRL + C to exit")
 
+	err := RecoverPendingCycles(ctx)
+	if err != nil {
+		handleAutoError(err)
+	}
+
 	var wg sync.WaitGroup
 	lock := make(chan struct{}, 1) // channel used as mutex
 
+	startPriceFeed(ctx)
+	stream := startUserStream(ctx, lock)
+
 	wg.Add(2)
-	go startNewCycle(&wg, lock)
-	go updateRunningCycles(&wg, lock)
+	go startNewCycle(ctx, &wg, lock)
+	go updateRunningCycles(ctx, &wg, lock, stream)
 
 	wg.Wait()
+	color.Yellow("Auto Mode stopped")
 }
@@ -1,15 +1,17 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/fatih/color"
-	"log"
 	"main/database"
+	"main/exchanges"
 	"os"
 	"strconv"
 )
 
-func Cancel() error {
+func Cancel(ctx context.Context) error {
 	if len(os.Args) < 3 {
 		color.Red("Id required")
 		color.Cyan("go run . -c 34")
@@ -30,29 +32,56 @@ func Cancel() error {
 	}
 
 	status := cycle.Status
-	if status == "completed" {
-		errMsg := "can't cancel completed cycle, only 'buy' or 'sell' is supported"
+	switch status {
+	case database.Pending, database.Buy, database.Sell, database.Stopping, database.Expired:
+	case database.Trailing:
+		// No order is open but the cycle holds what it bought, deleting it
+		// would leave that unsold and untracked
+		errMsg := fmt.Sprintf("can't cancel %s cycle, it holds what it bought and sells it once the price falls back", status)
 		color.Red(errMsg)
-		return fmt.Errorf(errMsg)
+		return errors.New(errMsg)
+	default:
+		errMsg := fmt.Sprintf("can't cancel %s cycle, only 'pending', 'buy', 'sell', 'stopping' or 'expired' is supported", status)
+		color.Red(errMsg)
+		return errors.New(errMsg)
 	}
 
 	exchange := cycle.Exchange
 	client := GetClientByExchange(exchange)
 
+	symbol, err := cycleSymbol(cycle)
+	if err != nil {
+		return err
+	}
+
 	if status == database.Buy {
 		buyId := cycle.Buy.ID
-		res, err := client.CancelOrder(buyId)
+		_, err := client.CancelOrder(ctx, symbol, buyId)
 		if err != nil {
-			log.Println(string(res))
 			return err
 		}
-	} else if status == database.Sell {
+	} else if status == database.Sell || status == database.Stopping {
 		sellId := cycle.Sell.ID
-		res, err := client.CancelOrder(sellId)
+		_, err := client.CancelOrder(ctx, symbol, sellId)
+		if err != nil {
+			return err
+		}
+	} else if status == database.Pending {
+		// The buy order may exist, only its client order id is known
+		clientId, err := clientOrderId(cycle.Id, "buy")
 		if err != nil {
-			log.Println(string(res))
 			return err
 		}
+		order, err := client.GetOrderByClientId(ctx, symbol, clientId)
+		if err != nil && !errors.Is(err, exchanges.ErrOrderNotFound) {
+			return err
+		}
+		if err == nil && order.IsActive() {
+			_, err = client.CancelOrder(ctx, symbol, order.Id)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	err = database.CycleDeleteById(id)
@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"context"
 	"github.com/joho/godotenv"
 	"log"
+	"main/database"
 	"os"
+	"strconv"
 	"testing"
 )
 
@@ -19,10 +22,43 @@ func TestCancel(t *testing.T) {
 	}
 
 	client := GetClientByExchange()
-	client.CheckConnection()
+	err = client.CheckConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.CancelOrder(context.Background(), getSymbol(), orderID)
+	if err != nil {
+		log.Fatal("Cannot cancel order: ", err)
+	}
+}
 
-	cancelOrder, err := client.CancelOrder(orderID)
+func TestCancelRefused(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	err := database.InitDatabase()
 	if err != nil {
-		log.Fatal("Cannot cancel order: ", string(cancelOrder))
+		t.Fatal(err)
+	}
+
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	// A trailing cycle holds what it bought, a completed one is over
+	for _, status := range []database.Status{database.Trailing, database.Completed} {
+		id, err := database.CycleNew(&database.Cycle{Exchange: "PAPER", Symbol: "BTC/USDC", Status: status, Quantity: 0.001})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		os.Args = []string{"bot", "-c", strconv.Itoa(int(id))}
+		err = Cancel(context.Background())
+		if err == nil {
+			t.Errorf("expected the %s cycle not canceled", status)
+		}
+
+		cycle, err := database.CycleGetById(int(id))
+		if err != nil || cycle.Status != status {
+			t.Errorf("expected the %s cycle kept, got %+v %v", status, cycle, err)
+		}
 	}
 }
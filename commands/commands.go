@@ -2,6 +2,7 @@ package commands
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,23 +11,32 @@ import (
 	"io"
 	"log"
 	"main/database"
+	"main/exchanges"
+	"main/exchanges/binance"
+	"main/exchanges/kraken"
+	"main/exchanges/kucoin"
 	"main/exchanges/mexc"
+	"main/exchanges/paper"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
 type ExchangeClient interface {
-	CheckConnection()
-	GetBalanceUSD() (float64, error)
-	GetLastPriceBTC() (float64, error)
+	CheckConnection(ctx context.Context) error
+	GetBalance(ctx context.Context, asset string) (float64, error)
+	GetLastPrice(ctx context.Context, symbol exchanges.Symbol) (float64, error)
 	SetBaseURL(url string)
-	CreateOrder(side, price, quantity string) ([]byte, error)
-	GetOrderById(id string) ([]byte, error)
-	IsFilled(id string) (bool, error)
-	CancelOrder(orderID string) ([]byte, error)
-	GetOpenOrders() ([]byte, error)
+	CreateOrder(ctx context.Context, symbol exchanges.Symbol, side, price, quantity, clientId string) (*exchanges.Order, error)
+	GetOrderById(ctx context.Context, symbol exchanges.Symbol, id string) (*exchanges.Order, error)
+	GetOrderByClientId(ctx context.Context, symbol exchanges.Symbol, clientId string) (*exchanges.Order, error)
+	CancelOrder(ctx context.Context, symbol exchanges.Symbol, orderID string) (*exchanges.Order, error)
+	GetOpenOrders(ctx context.Context, symbol exchanges.Symbol) ([]exchanges.Order, error)
+	GetOrderTrades(ctx context.Context, symbol exchanges.Symbol, orderID string) ([]exchanges.Trade, error)
+	GetSymbolFilters(ctx context.Context, symbol exchanges.Symbol) (exchanges.SymbolFilters, error)
+	GetKlines(ctx context.Context, symbol exchanges.Symbol, interval time.Duration, limit int) ([]exchanges.Kline, error)
 }
 
 const ConfigFilename = "bot.conf"
@@ -120,6 +130,18 @@ func GetClientByExchange(exchangeArg ...string) ExchangeClient {
 	case "MEXC":
 		client = mexc.NewClient()
 		client.SetBaseURL("https://api.mexc.co")
+	case "KUCOIN":
+		client = kucoin.NewClient()
+		client.SetBaseURL("https://api.kucoin.com")
+	case "BINANCE":
+		client = binance.NewClient()
+		client.SetBaseURL("https://api.binance.com")
+	case "KRAKEN":
+		client = kraken.NewClient()
+		client.SetBaseURL("https://api.kraken.com")
+	case "PAPER":
+		client = paper.NewClient()
+		client.SetBaseURL("https://api.mexc.co")
 	default:
 		fmt.Println("Unsupported exchange:", ex)
 		os.Exit(0)
@@ -128,6 +150,57 @@ func GetClientByExchange(exchangeArg ...string) ExchangeClient {
 	return client
 }
 
+// getSymbol returns the trading pair set by SYMBOL in bot.conf, BTC/USDC by default
+func getSymbol() exchanges.Symbol {
+	str := os.Getenv("SYMBOL")
+	if str == "" {
+		return exchanges.DefaultSymbol
+	}
+
+	symbol, err := exchanges.ParseSymbol(str)
+	if err != nil {
+		color.Red("SYMBOL env variable must be like BTC/USDC: %v", err)
+		os.Exit(0)
+	}
+	return symbol
+}
+
+// cycleSymbol returns the trading pair of a cycle
+func cycleSymbol(cycle *database.Cycle) (exchanges.Symbol, error) {
+	if cycle.Symbol == "" {
+		return exchanges.DefaultSymbol, nil
+	}
+	return exchanges.ParseSymbol(cycle.Symbol)
+}
+
+// Symbol filters by exchange and symbol. They rarely change, so they are
+// fetched once per run. The auto loop and the server both read them.
+var (
+	symbolFilters   = map[string]exchanges.SymbolFilters{}
+	symbolFiltersMu sync.Mutex
+)
+
+// getSymbolFilters returns the precision rules of symbol on exchange
+func getSymbolFilters(ctx context.Context, client ExchangeClient, exchange string, symbol exchanges.Symbol) (exchanges.SymbolFilters, error) {
+	symbolFiltersMu.Lock()
+	defer symbolFiltersMu.Unlock()
+
+	key := strings.ToUpper(exchange) + ":" + symbol.String()
+
+	filters, ok := symbolFilters[key]
+	if ok {
+		return filters, nil
+	}
+
+	filters, err := client.GetSymbolFilters(ctx, symbol)
+	if err != nil {
+		return filters, fmt.Errorf("error getting %s filters: %w", symbol, err)
+	}
+	symbolFilters[key] = filters
+
+	return filters, nil
+}
+
 func Log(message string) {
 	logDir := "logs"
 
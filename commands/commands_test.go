@@ -1,7 +1,11 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"main/exchanges"
+	"main/exchanges/paper"
+	"sync"
 	"testing"
 )
 
@@ -9,3 +13,25 @@ func TestGetClientByExchange(t *testing.T) {
 	client := GetClientByExchange()
 	fmt.Println(client)
 }
+
+func TestGetSymbolFilters(t *testing.T) {
+	symbolFilters = map[string]exchanges.SymbolFilters{}
+
+	// The auto loop and the server handlers read them at the same time
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			filters, err := getSymbolFilters(context.Background(), &paper.Client{}, "PAPER", exchanges.DefaultSymbol)
+			if err != nil || filters.TickSize != 0.01 {
+				t.Errorf("expected the paper filters, got %+v %v", filters, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(symbolFilters) != 1 {
+		t.Errorf("expected the filters fetched once, got %v", symbolFilters)
+	}
+}
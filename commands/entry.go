@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"main/exchanges"
+	"main/indicators"
+	"main/strategy"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getEntryInterval reads ENTRY_INTERVAL, the length of the klines the RSI
+// and moving average filters are computed on, 1h by default
+func getEntryInterval() time.Duration {
+	str := os.Getenv("ENTRY_INTERVAL")
+	if str == "" {
+		return time.Hour
+	}
+
+	interval, err := time.ParseDuration(str)
+	if err != nil || interval <= 0 {
+		color.Red("ENTRY_INTERVAL must be a duration like 15m, 1h or 24h")
+		os.Exit(0)
+	}
+	return interval
+}
+
+// getEntryPeriod reads the number of klines of an entry filter
+func getEntryPeriod(key string, fallback int) int {
+	str := os.Getenv(key)
+	if str == "" {
+		return fallback
+	}
+
+	period, err := strconv.Atoi(str)
+	if err != nil || period < 2 || period > 500 {
+		color.Red(key + " must be a number of klines between 2 and 500")
+		os.Exit(0)
+	}
+	return period
+}
+
+// getEntryRSIMax reads ENTRY_RSI_MAX, the RSI a new cycle enters under.
+// Empty or 0 disables it.
+func getEntryRSIMax() float64 {
+	str := os.Getenv("ENTRY_RSI_MAX")
+	if str == "" {
+		return 0
+	}
+
+	rsiMax, err := strconv.ParseFloat(str, 64)
+	if err != nil || rsiMax < 0 || rsiMax > 100 {
+		color.Red("ENTRY_RSI_MAX must be a number between 0 and 100")
+		os.Exit(0)
+	}
+	return rsiMax
+}
+
+// getEntryMA reads ENTRY_MA, SMA or EMA the price must be under for a new
+// cycle to enter. Empty disables it.
+func getEntryMA() string {
+	ma := strings.ToUpper(strings.TrimSpace(os.Getenv("ENTRY_MA")))
+	if ma != "" && ma != "SMA" && ma != "EMA" {
+		color.Red("ENTRY_MA must be SMA, EMA or empty")
+		os.Exit(0)
+	}
+	return ma
+}
+
+// getEntryMaxChange reads ENTRY_MAX_CHANGE, how far in percent the price
+// may have moved over 24h, up or down, for a new cycle to enter. Empty or 0
+// disables it.
+func getEntryMaxChange() float64 {
+	str := os.Getenv("ENTRY_MAX_CHANGE")
+	if str == "" {
+		return 0
+	}
+
+	maxChange, err := strconv.ParseFloat(str, 64)
+	if err != nil || maxChange < 0 {
+		color.Red("ENTRY_MAX_CHANGE must be a positive percentage")
+		os.Exit(0)
+	}
+	return maxChange
+}
+
+// entryKlines returns the last limit klines, at least needed of them
+func entryKlines(ctx context.Context, client strategy.KlineReader, symbol exchanges.Symbol, interval time.Duration, limit, needed int) ([]exchanges.Kline, error) {
+	klines, err := client.GetKlines(ctx, symbol, interval, min(limit, 500))
+	if err != nil {
+		return nil, fmt.Errorf("error getting klines: %w", err)
+	}
+	if len(klines) < needed {
+		return nil, fmt.Errorf("not enough %s klines of %s for the entry filters", symbol, interval)
+	}
+	return klines, nil
+}
+
+// entryRejection returns why a new cycle must not enter at price, empty when
+// the entry filters let it
+func entryRejection(ctx context.Context, client strategy.KlineReader, symbol exchanges.Symbol, price float64) (string, error) {
+	interval := getEntryInterval()
+
+	if rsiMax := getEntryRSIMax(); rsiMax > 0 {
+		period := getEntryPeriod("ENTRY_RSI_PERIOD", 14)
+
+		// The smoothing settles over a few periods
+		klines, err := entryKlines(ctx, client, symbol, interval, 3*period+1, period+1)
+		if err != nil {
+			return "", err
+		}
+		if rsi := indicators.RSI(klines, period); rsi >= rsiMax {
+			return fmt.Sprintf("RSI %.2f not under ENTRY_RSI_MAX %v", rsi, rsiMax), nil
+		}
+	}
+
+	if ma := getEntryMA(); ma != "" {
+		period := getEntryPeriod("ENTRY_MA_PERIOD", 50)
+
+		var average float64
+		if ma == "SMA" {
+			klines, err := entryKlines(ctx, client, symbol, interval, period, period)
+			if err != nil {
+				return "", err
+			}
+			average = indicators.SMA(klines, period)
+		} else {
+			// The start from the SMA fades over a few periods
+			klines, err := entryKlines(ctx, client, symbol, interval, 3*period, period)
+			if err != nil {
+				return "", err
+			}
+			average = indicators.EMA(klines, period)
+		}
+		if price >= average {
+			return fmt.Sprintf("price %.2f not under the %s %d of %.2f", price, ma, period, average), nil
+		}
+	}
+
+	if maxChange := getEntryMaxChange(); maxChange > 0 {
+		// Opened 24h ago
+		klines, err := entryKlines(ctx, client, symbol, time.Hour, 24, 24)
+		if err != nil {
+			return "", err
+		}
+		change := (price/klines[0].Open - 1) * 100
+		if math.Abs(change) > maxChange {
+			return fmt.Sprintf("price changed %+.2f%% over 24h, ENTRY_MAX_CHANGE is %v%%", change, maxChange), nil
+		}
+	}
+
+	return "", nil
+}
+
+// skipEntry tells whether the entry filters reject the new cycle of this
+// tick, and logs why
+func skipEntry(ctx context.Context) (bool, error) {
+	if getEntryRSIMax() == 0 && getEntryMA() == "" && getEntryMaxChange() == 0 {
+		return false, nil
+	}
+
+	exchange := getExchange()
+	symbol := getSymbol()
+
+	latest, err := getPriceFeed(exchange).Latest(ctx, symbol)
+	if err != nil {
+		return false, err
+	}
+
+	reason, err := entryRejection(ctx, GetClientByExchange(exchange), symbol, latest.Value)
+	if err != nil {
+		return false, fmt.Errorf("error checking entry filters: %w", err)
+	}
+	if reason == "" {
+		return false, nil
+	}
+
+	color.Yellow("New cycle skipped: %s", reason)
+	Log(fmt.Sprintf("New cycle skipped: %s", reason))
+	return true, nil
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"main/exchanges"
+	"main/exchanges/paper"
+	"testing"
+)
+
+func TestEntryRejection(t *testing.T) {
+	// Closes rising from 100 to 129
+	source := &klineSource{}
+	for i := 0; i < 30; i++ {
+		price := 100 + float64(i)
+		source.klines = append(source.klines, exchanges.Kline{Open: price - 1, High: price, Low: price - 1, Close: price})
+	}
+	client := &paper.Client{Source: source}
+
+	for _, test := range []struct {
+		name     string
+		env      map[string]string
+		price    float64
+		rejected bool
+	}{
+		{"no filter", nil, 120, false},
+		{"RSI only rising", map[string]string{"ENTRY_RSI_MAX": "70"}, 120, true},
+		{"under the SMA", map[string]string{"ENTRY_MA": "SMA", "ENTRY_MA_PERIOD": "10"}, 124, false},
+		{"over the SMA", map[string]string{"ENTRY_MA": "SMA", "ENTRY_MA_PERIOD": "10"}, 125, true},
+		{"under the EMA", map[string]string{"ENTRY_MA": "EMA", "ENTRY_MA_PERIOD": "10"}, 120, false},
+		{"over the EMA", map[string]string{"ENTRY_MA": "EMA", "ENTRY_MA_PERIOD": "10"}, 129, true},
+		// Opened at 105 24h ago
+		{"small change", map[string]string{"ENTRY_MAX_CHANGE": "20"}, 120, false},
+		{"large change", map[string]string{"ENTRY_MAX_CHANGE": "10"}, 120, true},
+		{"large fall", map[string]string{"ENTRY_MAX_CHANGE": "10"}, 90, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, key := range []string{"ENTRY_RSI_MAX", "ENTRY_MA", "ENTRY_MA_PERIOD", "ENTRY_MAX_CHANGE"} {
+				t.Setenv(key, test.env[key])
+			}
+
+			reason, err := entryRejection(context.Background(), client, exchanges.DefaultSymbol, test.price)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rejected := reason != ""; rejected != test.rejected {
+				t.Errorf("expected rejected %v, got %q", test.rejected, reason)
+			}
+		})
+	}
+
+	t.Setenv("ENTRY_MA", "SMA")
+	t.Setenv("ENTRY_MA_PERIOD", "50")
+	_, err := entryRejection(context.Background(), client, exchanges.DefaultSymbol, 120)
+	if err == nil {
+		t.Error("expected an error without enough klines")
+	}
+}
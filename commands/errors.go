@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"errors"
+	"github.com/fatih/color"
+	"log"
+	"main/exchanges"
+)
+
+// What the bot does after an error of the exchange
+type errorAction int
+
+const (
+	retryLater errorAction = iota // transient, the next run tries again
+	skipCycle                     // the cycle stays as is, the others go on
+	stopBot                       // nothing works until bot.conf is fixed
+)
+
+func actionFor(err error) errorAction {
+	switch {
+	case errors.Is(err, exchanges.ErrAuth):
+		return stopBot
+	case exchanges.Temporary(err):
+		return retryLater
+	}
+	return skipCycle
+}
+
+// handleAutoError logs the error of a run in auto mode. Only errors that
+// retrying can not fix stop the bot.
+func handleAutoError(err error) {
+	Log(err.Error())
+
+	switch actionFor(err) {
+	case stopBot:
+		log.Fatal(err)
+	case retryLater:
+		color.Yellow("%v - retrying on the next run", err)
+	default:
+		color.Red("%v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"main/exchanges"
+	"testing"
+)
+
+func TestActionFor(t *testing.T) {
+	for err, expected := range map[error]errorAction{
+		fmt.Errorf("error getting order: %w", exchanges.ErrNetwork):           retryLater,
+		fmt.Errorf("error creating sell order: %w", exchanges.ErrRateLimited): retryLater,
+		fmt.Errorf("error getting order: %w", exchanges.ErrOrderNotFound):     skipCycle,
+		fmt.Errorf("order failed: %w", exchanges.ErrInsufficientBalance):      skipCycle,
+		&exchanges.APIError{Exchange: "MEXC", Kind: exchanges.ErrAuth}:        stopBot,
+		errors.New("database is locked"):                                      skipCycle,
+	} {
+		if action := actionFor(err); action != expected {
+			t.Errorf("%v: expected action %d, got %d", err, expected, action)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"main/database"
+	"main/exchanges"
+	"os"
+	"time"
+)
+
+// getBuyMaxAge reads BUY_MAX_AGE, how long a buy order may stay unfilled, in
+// minutes without unit like the AUTO_INTERVAL settings. Empty or 0 keeps
+// buy orders open.
+func getBuyMaxAge() time.Duration {
+	str := os.Getenv("BUY_MAX_AGE")
+	if str == "" || str == "0" {
+		return 0
+	}
+	return dotenvToDuration("BUY_MAX_AGE")
+}
+
+// getBuyMaxDistance reads BUY_MAX_DISTANCE, how far under the last price an
+// unfilled buy order may be, a percentage like 5% or an amount like 3000.
+// Empty or 0 keeps buy orders open.
+func getBuyMaxDistance() (value float64, percent bool) {
+	return getPriceOffset("BUY_MAX_DISTANCE")
+}
+
+// buyExpiry tells why a buy order with nothing filled expired, empty when
+// it did not
+func buyExpiry(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, order *exchanges.Order) (string, error) {
+	if maxAge := getBuyMaxAge(); maxAge > 0 {
+		// Cycles created before the creation time was recorded
+		createdAt := cycle.CreatedAt
+		if createdAt == 0 {
+			createdAt = order.CreatedAt
+		}
+
+		age := time.Since(time.UnixMilli(createdAt))
+		if createdAt > 0 && age > maxAge {
+			return fmt.Sprintf("open for %s", age.Round(time.Minute)), nil
+		}
+	}
+
+	if value, percent := getBuyMaxDistance(); value > 0 {
+		lastPrice, err := getLastPrice(ctx, symbol)
+		if err != nil {
+			return "", err
+		}
+		if below(lastPrice, value, percent) > cycle.Buy.Price {
+			return fmt.Sprintf("%.2f under the last price %.2f", lastPrice-cycle.Buy.Price, lastPrice), nil
+		}
+	}
+
+	return "", nil
+}
+
+// expireBuy cancels the buy order and leaves the cycle expired. It returns
+// true when some was bought in the meantime, the cycle must then sell it.
+func expireBuy(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, order *exchanges.Order, reason string) (bool, error) {
+	canceled, err := cancelOrder(ctx, symbol, order.Id)
+	if err != nil {
+		return false, fmt.Errorf("error canceling expired buy: %w", err)
+	}
+
+	filled := buyFilled(cycle, order, canceled.ExecutedQty)
+	err = recordExecuted(cycle, "buyExecutedQty", &cycle.Buy.ExecutedQty, filled)
+	if err != nil {
+		return false, err
+	}
+	if filled > 0 {
+		fmt.Printf("%s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.YellowString("Order Buy expired with %.8f filled, selling it", filled),
+		)
+		return true, nil
+	}
+
+	_, err = database.CycleUpdate(cycle.Id, "status", database.Expired)
+	if err != nil {
+		return false, fmt.Errorf("error updating cycle status: %v", err)
+	}
+
+	fmt.Printf("%s %s %s\n",
+		color.YellowString("%d", cycle.Id),
+		color.RedString("Order Buy expired, %s - canceled", reason),
+		color.WhiteString("%s", order.Id),
+	)
+	Log(fmt.Sprintf("Cycle %d expired: %s", cycle.Id, reason))
+
+	return false, nil
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"context"
+	"main/database"
+	"main/exchanges"
+	"testing"
+	"time"
+)
+
+func TestExpireBuy(t *testing.T) {
+	t.Setenv("BUY_MAX_DISTANCE", "5%")
+	t.Setenv("BUY_MAX_AGE", "60")
+	ctx := context.Background()
+	paperClient, source := newPaperTest(t, 92000)
+
+	newCycle := func(createdAt time.Time) int {
+		buy, err := paperClient.CreateOrder(ctx, exchanges.DefaultSymbol, "BUY", "90000", "0.001", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := database.CycleNew(&database.Cycle{
+			Exchange:  "PAPER",
+			Symbol:    "BTC/USDC",
+			Status:    database.Buy,
+			Quantity:  0.001,
+			Buy:       database.BuyStruct{Price: 90000, ID: buy.Id},
+			Sell:      database.SellStruct{Price: 95000},
+			CreatedAt: createdAt.UnixMilli(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(id)
+	}
+
+	update := func(id int, price float64) *database.Cycle {
+		source.price = price
+		cycle, err := database.CycleGetById(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lastPrices = map[exchanges.Symbol]float64{}
+		err = handleBuy(ctx, cycle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cycle, err = database.CycleGetById(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cycle
+	}
+
+	// Expired once more than 5% under the last price
+	recent := newCycle(time.Now())
+	if cycle := update(recent, 94000); cycle.Status != database.Buy {
+		t.Fatalf("expected the buy 4.3%% under the price to wait, got %s", cycle.Status)
+	}
+	cycle := update(recent, 96000)
+	if cycle.Status != database.Expired {
+		t.Fatalf("expected the buy 6.3%% under the price to expire, got %s", cycle.Status)
+	}
+	order, _ := paperClient.GetOrderById(ctx, exchanges.DefaultSymbol, cycle.Buy.ID)
+	if order.Status != exchanges.OrderCanceled {
+		t.Errorf("expected the buy order canceled, got %s", order.Status)
+	}
+
+	// Expired after BUY_MAX_AGE minutes, whatever the price
+	old := newCycle(time.Now().Add(-2 * time.Hour))
+	if cycle := update(old, 92000); cycle.Status != database.Expired {
+		t.Errorf("expected the 2 hours old buy to expire, got %s", cycle.Status)
+	}
+}
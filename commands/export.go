@@ -75,19 +75,28 @@ func toCSV() {
 	header := []string{
 		"Id",
 		"Exchange",
+		"Symbol",
 		"Status",
 		"Quantity",
 		"BuyPrice",
 		"SellPrice",
 		"BuyId",
 		"SellId",
+		"Buy executed",
+		"Sell executed",
 		"Free balance",
 		"Dedicated balance",
 		"Buy offset",
 		"Sell offset",
 		"Percent",
-		"BTC price",
+		"Price",
 		"Absolute gain",
+		"Buy fee",
+		"Buy fee asset",
+		"Sell fee",
+		"Sell fee asset",
+		"Net gain",
+		"Volatility",
 	}
 	if err := writer.Write(header); err != nil {
 		panic(fmt.Errorf("failed to write header: %w", err))
@@ -103,19 +112,28 @@ func toCSV() {
 		row := []string{
 			fmt.Sprintf("%v", cycle.Id),
 			fmt.Sprintf("%v", cycle.Exchange),
+			fmt.Sprintf("%v", cycle.Symbol),
 			fmt.Sprintf("%v", cycle.Status),
 			fmt.Sprintf("%v", cycle.Quantity),
 			fmt.Sprintf("%v", cycle.Buy.Price),
 			fmt.Sprintf("%v", cycle.Sell.Price),
 			fmt.Sprintf("%v", cycle.Buy.ID),
 			fmt.Sprintf("%v", cycle.Sell.ID),
+			fmt.Sprintf("%v", cycle.Buy.ExecutedQty),
+			fmt.Sprintf("%v", cycle.Sell.ExecutedQty),
 			fmt.Sprintf("%v", cycle.MetaData.FreeBalanceUSD),
 			fmt.Sprintf("%v", cycle.MetaData.USDDedicated),
-			fmt.Sprintf("%v", cycle.Buy.Offset),
-			fmt.Sprintf("%v", cycle.Sell.Offset),
+			database.FormatOffset(cycle.Buy.Offset, cycle.Buy.OffsetType),
+			database.FormatOffset(cycle.Sell.Offset, cycle.Sell.OffsetType),
 			fmt.Sprintf("%v", cycle.MetaData.Percent),
 			fmt.Sprintf("%v", cycle.MetaData.BTCPrice),
 			fmt.Sprintf("%v", cycle.CalcProfit()),
+			fmt.Sprintf("%v", cycle.Buy.Fee),
+			fmt.Sprintf("%v", cycle.Buy.FeeAsset),
+			fmt.Sprintf("%v", cycle.Sell.Fee),
+			fmt.Sprintf("%v", cycle.Sell.FeeAsset),
+			fmt.Sprintf("%v", cycle.CalcNetProfit()),
+			fmt.Sprintf("%v", cycle.MetaData.Volatility),
 		}
 
 		if err := writer.Write(row); err != nil {
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"main/database"
+	"main/exchanges"
+	"main/tools"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errExposureLimit is returned by PrepareNewCycle when a new cycle would go
+// over one of the exposure limits
+var errExposureLimit = errors.New("exposure limit reached")
+
+// Whether the last new cycle was blocked, to notify once per blocked streak
+var exposureBlocked = false
+
+// getExposureLimit reads a limit of the open cycles, 0 when key is empty
+func getExposureLimit(key string) float64 {
+	str := os.Getenv(key)
+	if str == "" {
+		return 0
+	}
+
+	limit, err := strconv.ParseFloat(str, 64)
+	if err != nil || limit < 0 {
+		color.Red(key + " env variable must be a positive number")
+		os.Exit(0)
+	}
+	return limit
+}
+
+// exposure is what the open cycles of an exchange hold
+type exposure struct {
+	cycles    int
+	committed float64 // quote asset in buy orders or paid for what is not sold
+	held      float64 // base asset bought and not sold
+}
+
+// currentExposure sums the open cycles of exchange: committed counts the
+// cycles quoted like symbol, held those with its base asset
+func currentExposure(exchange string, symbol exchanges.Symbol) (exposure, error) {
+	var current exposure
+
+	cycles, err := database.CycleList()
+	if err != nil {
+		return current, fmt.Errorf("error getting cycles: %v", err)
+	}
+
+	for _, cycle := range cycles {
+		if !strings.EqualFold(cycle.Exchange, exchange) || !cycle.IsOpen() {
+			continue
+		}
+		current.cycles++
+
+		cycleSymbol, err := cycleSymbol(&cycle)
+		if err != nil {
+			return current, err
+		}
+
+		// A buy order locks all its quantity, bought assets count until sold
+		committed := cycle.Quantity
+		held := cycle.Buy.ExecutedQty
+		if cycle.Status != database.Pending && cycle.Status != database.Buy {
+			committed = cycle.Quantity - cycle.Sell.ExecutedQty
+			held = committed
+		}
+
+		if cycleSymbol.Quote == symbol.Quote {
+			current.committed += committed * cycle.Buy.Price
+		}
+		if cycleSymbol.Base == symbol.Base {
+			current.held += held
+		}
+	}
+
+	return current, nil
+}
+
+// checkExposure returns errExposureLimit when adding newCycle to the open
+// cycles goes over MAX_OPEN_CYCLES, MAX_COMMITTED in the quote asset or
+// MAX_HELD in the base asset
+func checkExposure(newCycle *database.Cycle, symbol exchanges.Symbol) error {
+	maxCycles := getExposureLimit("MAX_OPEN_CYCLES")
+	maxCommitted := getExposureLimit("MAX_COMMITTED")
+	maxHeld := getExposureLimit("MAX_HELD")
+	if maxCycles == 0 && maxCommitted == 0 && maxHeld == 0 {
+		return nil
+	}
+
+	current, err := currentExposure(newCycle.Exchange, symbol)
+	if err != nil {
+		return err
+	}
+
+	if maxCycles > 0 && float64(current.cycles+1) > maxCycles {
+		return fmt.Errorf("%w: %d open cycles, MAX_OPEN_CYCLES is %v", errExposureLimit, current.cycles, maxCycles)
+	}
+
+	committed := current.committed + newCycle.Quantity*newCycle.Buy.Price
+	if maxCommitted > 0 && committed > maxCommitted {
+		return fmt.Errorf("%w: %.2f %s committed with the new cycle, MAX_COMMITTED is %v", errExposureLimit, committed, symbol.Quote, maxCommitted)
+	}
+
+	// The new cycle holds its quantity once bought
+	held := current.held + newCycle.Quantity
+	if maxHeld > 0 && held > maxHeld {
+		return fmt.Errorf("%w: %.8f %s held with the new cycle, MAX_HELD is %v", errExposureLimit, held, symbol.Base, maxHeld)
+	}
+
+	return nil
+}
+
+// reportExposureBlock logs a new cycle blocked by a limit, and notifies the
+// first of a streak
+func reportExposureBlock(err error) {
+	color.Yellow("New cycle blocked: %v", err)
+	Log(fmt.Sprintf("New cycle blocked: %v", err))
+
+	if !exposureBlocked {
+		tools.Telegram(fmt.Sprintf("⛔ New cycle blocked: %v", err))
+	}
+	exposureBlocked = true
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"errors"
+	"main/database"
+	"main/exchanges"
+	"testing"
+)
+
+func TestCheckExposure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	err := database.InitDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cycle := range []database.Cycle{
+		{Exchange: "PAPER", Symbol: "BTC/USDC", Status: database.Buy, Quantity: 0.001, Buy: database.BuyStruct{Price: 90000}},
+		// The exchange matches whatever its case
+		{Exchange: "paper", Symbol: "BTC/USDC", Status: database.Sell, Quantity: 0.002, Buy: database.BuyStruct{Price: 100000}},
+		{Exchange: "PAPER", Symbol: "BTC/USDC", Status: database.Completed, Quantity: 0.005, Buy: database.BuyStruct{Price: 100000}},
+		{Exchange: "MEXC", Symbol: "BTC/USDC", Status: database.Sell, Quantity: 0.005, Buy: database.BuyStruct{Price: 100000}},
+	} {
+		_, err := database.CycleNew(&cycle)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 2 open cycles committing 290 USDC and holding 0.002 BTC, the new one
+	// adds 95 USDC and 0.001 BTC
+	newCycle := &database.Cycle{Exchange: "PAPER", Quantity: 0.001, Buy: database.BuyStruct{Price: 95000}}
+
+	for _, test := range []struct {
+		key, value string
+		blocked    bool
+	}{
+		{"MAX_OPEN_CYCLES", "", false},
+		{"MAX_OPEN_CYCLES", "3", false},
+		{"MAX_OPEN_CYCLES", "2", true},
+		{"MAX_COMMITTED", "400", false},
+		{"MAX_COMMITTED", "380", true},
+		{"MAX_HELD", "0.003", false},
+		{"MAX_HELD", "0.0025", true},
+	} {
+		t.Run(test.key+"="+test.value, func(t *testing.T) {
+			t.Setenv(test.key, test.value)
+			err := checkExposure(newCycle, exchanges.DefaultSymbol)
+			if blocked := errors.Is(err, errExposureLimit); blocked != test.blocked {
+				t.Errorf("expected blocked %v, got %v", test.blocked, err)
+			}
+		})
+	}
+}
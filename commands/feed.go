@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"github.com/fatih/color"
+	"main/feed"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Price feed of each exchange, shared by the updates, the new cycles and the
+// server
+var (
+	priceFeeds   = map[string]feed.PriceFeed{}
+	priceFeedsMu sync.Mutex
+)
+
+// priceMaxAge returns how long a price is used before being read again,
+// PRICE_MAX_AGE seconds, 10 by default
+func priceMaxAge() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("PRICE_MAX_AGE"))
+	if err != nil || seconds <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// getPriceFeed returns the price feed of exchange, the configured one when
+// empty. PRICE_FEED=WS streams the prices when the exchange can.
+func getPriceFeed(exchange string) feed.PriceFeed {
+	if exchange == "" {
+		exchange = os.Getenv("EXCHANGE")
+	}
+	exchange = strings.ToUpper(exchange)
+
+	priceFeedsMu.Lock()
+	defer priceFeedsMu.Unlock()
+
+	priceFeed, ok := priceFeeds[exchange]
+	if ok {
+		return priceFeed
+	}
+
+	client := GetClientByExchange(exchange)
+	priceFeed = feed.NewPoller(client, priceMaxAge())
+	if strings.ToUpper(os.Getenv("PRICE_FEED")) == "WS" {
+		streamer, ok := client.(feed.Streamer)
+		if ok {
+			priceFeed = feed.NewTicker(streamer, client, priceMaxAge())
+		} else {
+			color.Yellow("No price stream on %s, polling prices", exchange)
+		}
+	}
+
+	priceFeeds[exchange] = priceFeed
+	return priceFeed
+}
+
+// startPriceFeed keeps the price of the configured symbol fresh in auto mode,
+// until ctx is done
+func startPriceFeed(ctx context.Context) {
+	// Each read moves the paper replay on, it is only read when needed
+	if strings.ToUpper(os.Getenv("EXCHANGE")) == "PAPER" {
+		return
+	}
+	go getPriceFeed("").Run(ctx, getSymbol())
+}
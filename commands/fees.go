@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"main/database"
+	"main/exchanges"
+)
+
+// orderFee returns the commission charged for an order. Without it the net
+// profit and what is left to sell would be wrong, so a failure to fetch the
+// trades is returned and the cycle tries again on the next update.
+func orderFee(ctx context.Context, symbol exchanges.Symbol, orderId string) (float64, string, error) {
+	trades, err := client.GetOrderTrades(ctx, symbol, orderId)
+	if err != nil {
+		return 0, "", fmt.Errorf("error getting fees of order %s: %w", orderId, err)
+	}
+	fee, asset := exchanges.TotalFee(trades)
+	return fee, asset, nil
+}
+
+// Adds the fee of another order of the same side to fee
+func addFee(fee float64, asset string, more float64, moreAsset string) (float64, string) {
+	if more == 0 {
+		return fee, asset
+	}
+	if asset != "" && asset != moreAsset {
+		color.Red("Fees in %s and %s can not be added, ignoring %.8f %s", asset, moreAsset, more, moreAsset)
+		return fee, asset
+	}
+	return fee + more, moreAsset
+}
+
+// Average fill price of an order, its limit price when the exchange does not
+// report the executed amount
+func fillPrice(order *exchanges.Order) float64 {
+	if order.QuoteQty > 0 && order.ExecutedQty > 0 {
+		return order.QuoteQty / order.ExecutedQty
+	}
+	return order.Price
+}
+
+func CalcNetGainByCycle(cycle *database.Cycle) float64 {
+	return CalcAbsoluteGainByCycle(cycle) - cycle.Fees()
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"main/database"
+	"main/exchanges"
+	"main/exchanges/paper"
+	"testing"
+)
+
+// feeClient charges 0.1 USDC per order, once its trades can be read
+type feeClient struct {
+	*paper.Client
+	err error
+}
+
+func (c *feeClient) GetOrderTrades(ctx context.Context, symbol exchanges.Symbol, orderID string) ([]exchanges.Trade, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return []exchanges.Trade{{OrderId: orderID, Fee: 0.1, FeeAsset: symbol.Quote}}, nil
+}
+
+func TestSellFee(t *testing.T) {
+	paperClient, _ := newPaperTest(t, 100000)
+	fees := &feeClient{Client: paperClient, err: errors.New("trades unavailable")}
+	client = fees
+
+	// Bought earlier, the sell fills at once
+	buy, err := paperClient.CreateOrder(context.Background(), exchanges.DefaultSymbol, "BUY", "100000", "0.001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = paperClient.GetLastPrice(context.Background(), exchanges.DefaultSymbol)
+	sell, err := paperClient.CreateOrder(context.Background(), exchanges.DefaultSymbol, "SELL", "100000", "0.001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = paperClient.GetLastPrice(context.Background(), exchanges.DefaultSymbol)
+
+	id, err := database.CycleNew(&database.Cycle{
+		Exchange: "PAPER",
+		Symbol:   "BTC/USDC",
+		Status:   database.Sell,
+		Quantity: 0.001,
+		Buy:      database.BuyStruct{Price: 100000, ID: buy.Id},
+		Sell:     database.SellStruct{Price: 100000, ID: sell.Id},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func() *database.Cycle {
+		cycle, err := database.CycleGetById(int(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cycle
+	}
+
+	// Without the fee the cycle waits for the next update
+	err = handleSell(context.Background(), get())
+	if err == nil {
+		t.Error("expected an error while the trades can not be read")
+	}
+	if cycle := get(); cycle.Status != database.Sell || cycle.Sell.FeeAsset != "" {
+		t.Fatalf("expected the cycle still selling without fee, got %s %v %s", cycle.Status, cycle.Sell.Fee, cycle.Sell.FeeAsset)
+	}
+
+	fees.err = nil
+	err = handleSell(context.Background(), get())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycle := get(); cycle.Status != database.Completed || cycle.Sell.Fee != 0.1 || cycle.Sell.FeeAsset != "USDC" {
+		t.Errorf("expected the cycle completed with a 0.1 USDC fee, got %s %v %s", cycle.Status, cycle.Sell.Fee, cycle.Sell.FeeAsset)
+	}
+}
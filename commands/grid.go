@@ -0,0 +1,251 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"main/database"
+	"main/exchanges"
+	"main/strategy"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getGridLevels reads GRID_LEVELS, how many buy orders the grid keeps under
+// the price. Empty or 0 places a single cycle at a time.
+func getGridLevels() int {
+	str := os.Getenv("GRID_LEVELS")
+	if str == "" {
+		return 0
+	}
+
+	levels, err := strconv.Atoi(str)
+	if err != nil || levels < 0 || levels > 100 {
+		color.Red("GRID_LEVELS must be a number of levels between 0 and 100")
+		os.Exit(0)
+	}
+	return levels
+}
+
+// getGridStep reads GRID_STEP, the space between two levels, a percentage of
+// the anchor like 1% or an amount like 500. The deepest level must stay above
+// 0 in percentage.
+func getGridStep() (value float64, percent bool) {
+	value, percent = getPriceOffset("GRID_STEP")
+	if value == 0 {
+		color.Red("GRID_STEP is required with GRID_LEVELS")
+		os.Exit(0)
+	}
+	if percent && float64(getGridLevels())*value >= 100 {
+		color.Red("GRID_LEVELS x GRID_STEP must stay under 100%%")
+		os.Exit(0)
+	}
+	return value, percent
+}
+
+// gridOffsets returns the offsets from the anchor of a level: it buys level
+// steps under the anchor and sells one step higher
+func gridOffsets(level int) (buy, sell float64, offsetType database.OffsetType) {
+	value, percent := getGridStep()
+	offsetType = database.OffsetAbsolute
+	if percent {
+		offsetType = database.OffsetPercent
+	}
+	return -float64(level) * value, -float64(level-1) * value, offsetType
+}
+
+// gridEntry buys PERCENT of the free balance on level of the grid hanging
+// from anchor
+func gridEntry(market strategy.Market, level int, anchor float64) *strategy.Entry {
+	buy, sell, offsetType := gridOffsets(level)
+	entry := &strategy.Entry{
+		BuyPrice:       market.Filters.RoundPrice(database.ApplyOffset(anchor, buy, offsetType)),
+		SellPrice:      market.Filters.RoundPrice(database.ApplyOffset(anchor, sell, offsetType)),
+		BuyOffset:      buy,
+		BuyOffsetType:  offsetType,
+		SellOffset:     sell,
+		SellOffsetType: offsetType,
+		Percent:        market.Percent,
+	}
+	entry.Quantity = CalcAmountBTC(CalcAmountUSD(market.Balance, entry.Percent), entry.BuyPrice)
+
+	from := fmt.Sprintf("from %.2f", anchor)
+	entry.BuyRule = fmt.Sprintf("grid level %d, %s %s", level, database.FormatOffset(buy, offsetType), from)
+	entry.SellRule = fmt.Sprintf("grid level %d, %s %s", level, database.FormatOffset(sell, offsetType), from)
+	return entry
+}
+
+func gridAnchorKey(exchange string, symbol exchanges.Symbol) string {
+	return "grid_anchor_" + exchange + "_" + symbol.String()
+}
+
+// Time the anchor was last moved, in unix milliseconds
+func gridAnchoredAtKey(exchange string, symbol exchanges.Symbol) string {
+	return "grid_anchored_at_" + exchange + "_" + symbol.String()
+}
+
+// getGridAnchor returns the price the levels hang from, 0 before the grid
+// first placed its orders
+func getGridAnchor(exchange string, symbol exchanges.Symbol) (float64, error) {
+	value, err := database.CfgGet(gridAnchorKey(exchange, symbol))
+	if err != nil || value == "" {
+		return 0, err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// setGridAnchor moves the anchor of the grid to price
+func setGridAnchor(exchange string, symbol exchanges.Symbol, price float64) error {
+	err := database.CfgSet(gridAnchorKey(exchange, symbol), strconv.FormatFloat(price, 'f', -1, 64))
+	if err != nil {
+		return err
+	}
+	return database.CfgSet(gridAnchoredAtKey(exchange, symbol), strconv.FormatInt(time.Now().UnixMilli(), 10))
+}
+
+// gridLevels returns the open cycle of each level of the grid, and the
+// levels whose buy expired since the anchor last moved
+func gridLevels(exchange string, symbol exchanges.Symbol) (open map[int]database.Cycle, expired map[int]bool, err error) {
+	cycles, err := database.CycleList()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting cycles: %v", err)
+	}
+
+	value, err := database.CfgGet(gridAnchoredAtKey(exchange, symbol))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting grid anchor time: %v", err)
+	}
+	anchoredAt, _ := strconv.ParseInt(value, 10, 64)
+
+	open = map[int]database.Cycle{}
+	expired = map[int]bool{}
+	for _, cycle := range cycles {
+		if cycle.GridLevel == 0 || !strings.EqualFold(cycle.Exchange, exchange) || cycle.Symbol != symbol.String() {
+			continue
+		}
+		if cycle.IsOpen() {
+			open[cycle.GridLevel] = cycle
+		} else if cycle.Status == database.Expired && cycle.CreatedAt >= anchoredAt {
+			expired[cycle.GridLevel] = true
+		}
+	}
+	return open, expired, nil
+}
+
+// Grid keeps a buy order on each level of the grid under the price. A level
+// is free again once its cycle completed or stopped, an expired one waits for
+// the anchor to move. The grid hangs from the price it was started at, and
+// moves to the last price once none of its cycles is open.
+func Grid(ctx context.Context) error {
+	exchange := getExchange()
+	symbol := getSymbol()
+
+	open, expired, err := gridLevels(exchange, symbol)
+	if err != nil {
+		return err
+	}
+
+	latest, err := getPriceFeed(exchange).Latest(ctx, symbol)
+	if err != nil {
+		return err
+	}
+
+	anchor, err := getGridAnchor(exchange, symbol)
+	if err != nil {
+		return fmt.Errorf("error getting grid anchor: %v", err)
+	}
+	if anchor == 0 || len(open) == 0 {
+		anchor = latest.Value
+		err = setGridAnchor(exchange, symbol, anchor)
+		if err != nil {
+			return fmt.Errorf("error setting grid anchor: %v", err)
+		}
+		expired = map[int]bool{}
+	}
+
+	for level := 1; level <= getGridLevels(); level++ {
+		// Placed again at the same price, it would expire again
+		if _, ok := open[level]; ok || expired[level] {
+			continue
+		}
+
+		// The price is under the level, it waits for the price to come back
+		buy, _, offsetType := gridOffsets(level)
+		price := database.ApplyOffset(anchor, buy, offsetType)
+		if price >= latest.Value {
+			continue
+		}
+		// Steps in amount may hang the deepest levels under 0
+		if price <= 0 {
+			break
+		}
+
+		newCycle, err := prepareCycle(ctx, level, anchor)
+		if errors.Is(err, errExposureLimit) {
+			reportExposureBlock(err)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error preparing grid level %d: %w", level, err)
+		}
+		exposureBlocked = false
+
+		err = placeNewCycle(ctx, newCycle)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gridRung is a level of the grid as the dashboard shows it
+type gridRung struct {
+	Level     int
+	BuyPrice  float64
+	SellPrice float64
+	Cycle     *database.Cycle // open cycle of the level, nil when free
+}
+
+// gridLadder returns the levels of the configured grid, nil when the grid is
+// disabled or not started
+func gridLadder() ([]gridRung, error) {
+	levels := getGridLevels()
+	if levels == 0 {
+		return nil, nil
+	}
+
+	exchange := getExchange()
+	symbol := getSymbol()
+
+	anchor, err := getGridAnchor(exchange, symbol)
+	if err != nil || anchor == 0 {
+		return nil, err
+	}
+
+	open, _, err := gridLevels(exchange, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	ladder := make([]gridRung, 0, levels)
+	for level := 1; level <= levels; level++ {
+		buy, sell, offsetType := gridOffsets(level)
+		rung := gridRung{
+			Level:     level,
+			BuyPrice:  database.ApplyOffset(anchor, buy, offsetType),
+			SellPrice: database.ApplyOffset(anchor, sell, offsetType),
+		}
+		if rung.BuyPrice <= 0 {
+			break
+		}
+		if cycle, ok := open[level]; ok {
+			rung.Cycle = &cycle
+		}
+		ladder = append(ladder, rung)
+	}
+	return ladder, nil
+}
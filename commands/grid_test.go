@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"context"
+	"main/database"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestGridOffsets(t *testing.T) {
+	t.Setenv("GRID_STEP", "1%")
+	buy, sell, offsetType := gridOffsets(3)
+	if buy != -3 || sell != -2 || offsetType != database.OffsetPercent {
+		t.Errorf("expected -3%% / -2%%, got %v / %v %s", buy, sell, offsetType)
+	}
+
+	t.Setenv("GRID_STEP", "500")
+	buy, sell, offsetType = gridOffsets(1)
+	if buy != -500 || sell != 0 || offsetType != database.OffsetAbsolute {
+		t.Errorf("expected -500 / 0, got %v / %v %s", buy, sell, offsetType)
+	}
+}
+
+func TestGrid(t *testing.T) {
+	t.Setenv("SYMBOL", "BTC/USDC")
+	t.Setenv("PERCENT", "10")
+	t.Setenv("GRID_LEVELS", "3")
+	t.Setenv("GRID_STEP", "1%")
+	ctx := context.Background()
+	_, source := newPaperTest(t, 100000)
+
+	// The clients built for the new cycles replay a steady price
+	csv := filepath.Join(t.TempDir(), "prices.csv")
+	err := os.WriteFile(csv, []byte(strings.Repeat("100000\n", 100)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PAPER_FEED", "CSV")
+	t.Setenv("PAPER_CSV", csv)
+
+	levels := func() []database.Cycle {
+		cycles, err := database.CycleList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var open []database.Cycle
+		for _, cycle := range cycles {
+			if cycle.IsOpen() {
+				open = append(open, cycle)
+			}
+		}
+		sort.Slice(open, func(i, j int) bool { return open[i].GridLevel < open[j].GridLevel })
+		return open
+	}
+
+	// A buy order on each level, selling one step higher
+	err = Grid(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := levels()
+	if len(open) != 3 {
+		t.Fatalf("expected 3 open cycles, got %d", len(open))
+	}
+	for i, cycle := range open {
+		buy, sell := 100000-float64(i+1)*1000, 100000-float64(i)*1000
+		if cycle.GridLevel != i+1 || cycle.Status != database.Buy || cycle.Buy.Price != buy || cycle.Sell.Price != sell {
+			t.Errorf("expected level %d buying at %v and selling at %v, got level %d %s %v / %v",
+				i+1, buy, sell, cycle.GridLevel, cycle.Status, cycle.Buy.Price, cycle.Sell.Price)
+		}
+	}
+
+	// The full ladder places nothing more
+	err = Grid(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open = levels(); len(open) != 3 {
+		t.Fatalf("expected 3 open cycles, got %d", len(open))
+	}
+
+	// A completed level is placed again from the same anchor, even with the
+	// price moved
+	_, err = database.CycleUpdate(open[1].Id, "status", database.Completed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.price = 100500
+	err = Grid(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open = levels()
+	if len(open) != 3 || open[1].GridLevel != 2 || open[1].Buy.Price != 98000 {
+		t.Fatalf("expected level 2 buying again at 98000, got %+v", open)
+	}
+
+	// Levels above the price wait for it to come back
+	_, err = database.CycleUpdate(open[0].Id, "status", database.Completed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.price = 98500
+	err = Grid(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open = levels(); len(open) != 2 {
+		t.Fatalf("expected level 1 free under the price, got %d open cycles", len(open))
+	}
+
+	ladder, err := gridLadder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ladder) != 3 || ladder[0].Cycle != nil || ladder[1].Cycle == nil || ladder[2].BuyPrice != 97000 {
+		t.Errorf("unexpected ladder %+v", ladder)
+	}
+}
+
+func TestGridUnderZero(t *testing.T) {
+	t.Setenv("SYMBOL", "BTC/USDC")
+	t.Setenv("PERCENT", "10")
+	t.Setenv("GRID_LEVELS", "3")
+	t.Setenv("GRID_STEP", "40000")
+	ctx := context.Background()
+	newPaperTest(t, 100000)
+
+	csv := filepath.Join(t.TempDir(), "prices.csv")
+	err := os.WriteFile(csv, []byte(strings.Repeat("100000\n", 100)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PAPER_FEED", "CSV")
+	t.Setenv("PAPER_CSV", csv)
+
+	// The third level would buy at -20000, it is left out
+	err = Grid(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycles, err := database.CycleList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cycles) != 2 {
+		t.Errorf("expected 2 levels placed, got %d", len(cycles))
+	}
+	for _, cycle := range cycles {
+		if cycle.Buy.Price <= 0 {
+			t.Errorf("expected buy prices above 0, got %v on level %d", cycle.Buy.Price, cycle.GridLevel)
+		}
+	}
+
+	ladder, err := gridLadder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ladder) != 2 {
+		t.Errorf("expected a ladder of 2 levels, got %+v", ladder)
+	}
+}
+
+func TestGridLevelsKept(t *testing.T) {
+	t.Setenv("SYMBOL", "BTC/USDC")
+	t.Setenv("PERCENT", "10")
+	t.Setenv("GRID_LEVELS", "2")
+	t.Setenv("GRID_STEP", "1%")
+	ctx := context.Background()
+	_, source := newPaperTest(t, 100000)
+
+	csv := filepath.Join(t.TempDir(), "prices.csv")
+	err := os.WriteFile(csv, []byte(strings.Repeat("100000\n", 100)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PAPER_FEED", "CSV")
+	t.Setenv("PAPER_CSV", csv)
+
+	err = Grid(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycles, err := database.CycleList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cycles) != 2 || cycles[1].GridLevel != 1 {
+		t.Fatalf("expected 2 levels placed, got %+v", cycles)
+	}
+	first := cycles[1].Id
+
+	// The exchange of a level matches whatever its case
+	_, err = database.CycleUpdate(first, "exchange", "paper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Grid(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycles, _ = database.CycleList(); len(cycles) != 2 {
+		t.Fatalf("expected level 1 kept open, got %d cycles", len(cycles))
+	}
+
+	// Expired, the level waits for the anchor to move
+	_, err = database.CycleUpdate(first, "status", database.Expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Grid(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycles, _ = database.CycleList(); len(cycles) != 2 {
+		t.Fatalf("expected the expired level left free, got %d cycles", len(cycles))
+	}
+
+	// Nothing open, the grid moves to the price and places both levels
+	_, err = database.CycleUpdate(cycles[0].Id, "status", database.Completed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.price = 101000
+	err = Grid(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycles, _ = database.CycleList()
+	if len(cycles) != 4 || cycles[1].GridLevel != 1 || cycles[1].Buy.Price != 99990 {
+		t.Errorf("expected level 1 placed again at 99990, got %+v", cycles)
+	}
+}
@@ -1,27 +1,25 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 )
 
-func List() {
+func List(ctx context.Context) {
 	client := GetClientByExchange()
 
-	client.CheckConnection()
-
-	orders, err := client.GetOpenOrders()
+	err := client.CheckConnection(ctx)
 	if err != nil {
-		panic(err)
+		log.Fatal(err)
 	}
 
-	var prettyJSON interface{}
-	err = json.Unmarshal(orders, &prettyJSON)
+	orders, err := client.GetOpenOrders(ctx, getSymbol())
 	if err != nil {
-		log.Fatalf("Error unmarshalling orders JSON: %v", err)
+		panic(err)
 	}
 
-	indentedOrders, err := json.MarshalIndent(prettyJSON, "", "  ")
+	indentedOrders, err := json.MarshalIndent(orders, "", "  ")
 	if err != nil {
 		log.Fatalf("Error marshalling orders to indented JSON: %v", err)
 	}
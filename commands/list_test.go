@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"github.com/joho/godotenv"
 	"testing"
 )
@@ -11,5 +12,5 @@ func TestList(t *testing.T) {
 		t.Fatalf("Error loading ../bot.conf: %v", err)
 	}
 
-	List()
+	List(context.Background())
 }
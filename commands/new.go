@@ -1,14 +1,18 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/buger/jsonparser"
 	"github.com/fatih/color"
-	"log"
 	"main/database"
+	"main/exchanges"
+	"main/strategy"
 	"main/tools"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func CalcAmountUSD(freeBalance float64, percent float64) float64 {
@@ -19,107 +23,219 @@ func CalcAmountBTC(availableUSD, priceBTC float64) float64 {
 	return availableUSD / priceBTC
 }
 
-func New() error {
+func New(ctx context.Context) error {
 	MainMiddleware()
 
-	newCycle, err := PrepareNewCycle()
+	skip, err := skipEntry(ctx)
+	if err != nil || skip {
+		return err
+	}
+
+	if getGridLevels() > 0 {
+		return Grid(ctx)
+	}
+
+	newCycle, err := PrepareNewCycle(ctx)
+	if errors.Is(err, errNoEntry) {
+		color.Yellow("No new cycle: %v", err)
+		return nil
+	}
+	if errors.Is(err, errExposureLimit) {
+		reportExposureBlock(err)
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("error preparing new cycle: %v", err)
+		return fmt.Errorf("error preparing new cycle: %w", err)
 	}
+	exposureBlocked = false
 
-	client := GetClientByExchange(newCycle.Exchange)
+	return placeNewCycle(ctx, newCycle)
+}
 
-	// Prepare Order
-	buyPriceStr := fmt.Sprintf("%.2f", newCycle.Buy.Price)
-	buyBTCQuantityStr := fmt.Sprintf("%.6f", newCycle.Quantity)
+// placeNewCycle inserts the cycle then places its buy order
+func placeNewCycle(ctx context.Context, newCycle *database.Cycle) error {
+	client := GetClientByExchange(newCycle.Exchange)
 
-	body, err := client.CreateOrder("BUY", buyPriceStr, buyBTCQuantityStr)
+	symbol, err := cycleSymbol(newCycle)
 	if err != nil {
-		color.Red("Order failed:", err)
-		tools.Telegram("Order failed: " + err.Error())
-		os.Exit(0)
+		return err
 	}
-	buyOrderId, _, _, err := jsonparser.Get(body, "orderId")
+
+	filters, err := getSymbolFilters(ctx, client, newCycle.Exchange, symbol)
 	if err != nil {
-		tools.Telegram("Order failed: " + err.Error())
-		log.Fatal("Order failed: " + err.Error())
+		return err
 	}
 
-	newCycle.Buy.ID = string(buyOrderId)
-	newCycle.Status = database.Buy
+	// Prepare Order
+	buyPriceStr := filters.FormatPrice(newCycle.Buy.Price)
+	buyQuantityStr := filters.FormatQuantity(newCycle.Quantity)
 
-	// Insert in database
+	// Insert in database before the order, a crash in between leaves a
+	// pending cycle that RecoverPendingCycles resolves
+	newCycle.Status = database.Pending
+	newCycle.CreatedAt = time.Now().UnixMilli()
 	newId, err := database.CycleNew(newCycle)
 	if err != nil {
 		return fmt.Errorf("error inserting new cycle in database: %v", err)
 	}
+	newCycle.Id = int(newId)
+
+	clientId, err := clientOrderId(newCycle.Id, "buy")
+	if err != nil {
+		return err
+	}
+
+	order, err := client.CreateOrder(ctx, symbol, "BUY", buyPriceStr, buyQuantityStr, clientId)
+	if err != nil {
+		tools.Telegram("Order failed: " + err.Error())
+
+		// Without an answer the order may exist, the recovery will tell
+		if !exchanges.Temporary(err) {
+			deleteErr := database.CycleDeleteById(newCycle.Id)
+			if deleteErr != nil {
+				color.Red("Error deleting cycle %d: %v", newCycle.Id, deleteErr)
+			}
+		}
+		return fmt.Errorf("order failed: %w", err)
+	}
+
+	err = confirmBuy(newCycle.Id, order.Id)
+	if err != nil {
+		return err
+	}
+	newCycle.Buy.ID = order.Id
+	newCycle.Status = database.Buy
+
 	message := "New Cycle successfully inserted in database"
 	color.Green(message)
 	Log(message)
 
-	newCycle.Id = int(newId)
-
 	notifTelegram(newCycle)
 
 	return nil
 }
 
 // PrepareNewCycle Prepare new cycle before place order and insert in db
-func PrepareNewCycle() (*database.Cycle, error) {
-	newCycle := database.Cycle{}
+func PrepareNewCycle(ctx context.Context) (*database.Cycle, error) {
+	return prepareCycle(ctx, 0, 0)
+}
+
+// prepareCycle prepares a cycle on gridLevel of the grid hanging from
+// anchor, or the entry of the strategy when gridLevel is 0
+func prepareCycle(ctx context.Context, gridLevel int, anchor float64) (*database.Cycle, error) {
+	newCycle := database.Cycle{GridLevel: gridLevel}
 
 	// Exchange
 	exchange := getExchange()
 	newCycle.Exchange = exchange
 
-	// Percent
-	percent := getPercent()
-	newCycle.MetaData.Percent = percent
-
-	// BuyOffset
-	buyOffset := getOffset("BUY_OFFSET")
-	newCycle.Buy.Offset = buyOffset
-
-	// SellOffset
-	sellOffset := getOffset("SELL_OFFSET")
-	newCycle.Sell.Offset = sellOffset
+	// Symbol
+	symbol := getSymbol()
+	newCycle.Symbol = symbol.String()
 
 	client := GetClientByExchange(exchange)
-	client.CheckConnection()
-
-	// BTCPrice
-	btcPrice, err := client.GetLastPriceBTC()
+	err := client.CheckConnection(ctx)
 	if err != nil {
 		return nil, err
 	}
-	newCycle.MetaData.BTCPrice = btcPrice
 
-	// BuyPrice
-	buyPrice := btcPrice + float64(newCycle.Buy.Offset)
-	newCycle.Buy.Price = buyPrice
+	// Price
+	latest, err := getPriceFeed(exchange).Latest(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	price := latest.Value
+	newCycle.MetaData.BTCPrice = price
 
-	// Sell Price
-	sellPrice := btcPrice + float64(newCycle.Sell.Offset)
-	newCycle.Sell.Price = sellPrice
+	// Precision rules of the exchange
+	filters, err := getSymbolFilters(ctx, client, exchange, symbol)
+	if err != nil {
+		return nil, err
+	}
 
-	// FreeBalanceUSD
-	freeBalance, err := client.GetBalanceUSD()
+	// FreeBalance in quote asset
+	freeBalance, err := client.GetBalance(ctx, symbol.Quote)
 	if err != nil {
-		return nil, fmt.Errorf("error getting free balance: %v", err)
+		return nil, fmt.Errorf("error getting free balance: %w", err)
 	}
 	if freeBalance < 10 {
-		color.Red("At least 10$ needed")
-		os.Exit(0)
+		return nil, fmt.Errorf("at least 10$ needed: %w", exchanges.ErrInsufficientBalance)
 	}
 	newCycle.MetaData.FreeBalanceUSD = freeBalance
 
+	market := strategy.Market{
+		Exchange: exchange,
+		Symbol:   symbol,
+		Price:    price,
+		Filters:  filters,
+		Klines:   client,
+		Balance:  freeBalance,
+		Percent:  getPercent(),
+	}
+
+	// Entry, on the grid level or from the strategy
+	var entry *strategy.Entry
+	if gridLevel > 0 {
+		entry = gridEntry(market, gridLevel, anchor)
+	} else {
+		open, err := openCycles(exchange, symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		market.Offsets, err = entryOffsets(ctx, client, symbol, price)
+		if err != nil {
+			return nil, err
+		}
+
+		name, s := getStrategy()
+		entry, err = s.Entry(ctx, market, open)
+		if err != nil {
+			return nil, fmt.Errorf("error getting the entry of the %s strategy: %w", name, err)
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("%w from the %s strategy", errNoEntry, name)
+		}
+	}
+
+	newCycle.MetaData.Percent = entry.Percent
+	newCycle.MetaData.Volatility = entry.Volatility
+	newCycle.Buy.Offset, newCycle.Buy.OffsetType = entry.BuyOffset, entry.BuyOffsetType
+	newCycle.Sell.Offset, newCycle.Sell.OffsetType = entry.SellOffset, entry.SellOffsetType
+	if newCycle.Buy.OffsetType == "" {
+		newCycle.Buy.OffsetType = database.OffsetAbsolute
+	}
+	if newCycle.Sell.OffsetType == "" {
+		newCycle.Sell.OffsetType = database.OffsetAbsolute
+	}
+
+	// BuyPrice
+	newCycle.Buy.Price = filters.RoundPrice(entry.BuyPrice)
+
+	// Sell Price
+	newCycle.Sell.Price = filters.RoundPrice(entry.SellPrice)
+
+	// Quantity in base asset
+	newCycle.Quantity = filters.RoundQuantity(entry.Quantity)
+
 	// USDDedicated
-	usdDedicated := CalcAmountUSD(freeBalance, newCycle.MetaData.Percent)
-	newCycle.MetaData.USDDedicated = usdDedicated
+	newCycle.MetaData.USDDedicated = newCycle.Quantity * newCycle.Buy.Price
+
+	// Reject the cycle before placing an order the exchange would refuse
+	err = filters.Validate(newCycle.Buy.Price, newCycle.Quantity)
+	if err != nil {
+		return nil, fmt.Errorf("buy order rejected by %s filters: %v", symbol, err)
+	}
+	err = filters.Validate(newCycle.Sell.Price, newCycle.Quantity)
+	if err != nil {
+		return nil, fmt.Errorf("sell order rejected by %s filters: %v", symbol, err)
+	}
 
-	// BTCQuantity
-	btcQuantity := CalcAmountBTC(newCycle.MetaData.USDDedicated, newCycle.Buy.Price)
-	newCycle.Quantity = btcQuantity
+	// Limits of the open cycles
+	err = checkExposure(&newCycle, symbol)
+	if err != nil {
+		return nil, err
+	}
 
 	// Display Data
 	const fieldWidth = 27
@@ -130,23 +246,35 @@ func PrepareNewCycle() (*database.Cycle, error) {
 		color.YellowString(newCycle.Exchange),
 	)
 
+	fmt.Printf(formatString,
+		color.CyanString("Symbol"),
+		color.YellowString(newCycle.Symbol),
+	)
+
 	fmt.Printf(formatString,
 		color.CyanString("Percent"),
 		color.YellowString(fmt.Sprintf("%.2f", newCycle.MetaData.Percent)),
 	)
 
+	if newCycle.MetaData.Volatility > 0 {
+		fmt.Printf(formatString,
+			color.CyanString("Volatility"),
+			color.YellowString("%.2f", newCycle.MetaData.Volatility),
+		)
+	}
+
 	fmt.Printf(formatString,
 		color.CyanString("Buy Offset"),
-		color.YellowString(strconv.Itoa(newCycle.Buy.Offset)),
+		color.YellowString("%s (%+.2f)", entry.BuyRule, newCycle.Buy.Price-price),
 	)
 
 	fmt.Printf(formatString,
 		color.CyanString("Sell Offset"),
-		color.YellowString(strconv.Itoa(newCycle.Sell.Offset)),
+		color.YellowString("%s (%+.2f)", entry.SellRule, newCycle.Sell.Price-price),
 	)
 
 	fmt.Printf(formatString,
-		color.CyanString("BTC Price"),
+		color.CyanString(symbol.Base+" Price"),
 		color.YellowString("%.2f", newCycle.MetaData.BTCPrice),
 	)
 
@@ -171,7 +299,7 @@ func PrepareNewCycle() (*database.Cycle, error) {
 	)
 
 	fmt.Printf(formatString,
-		color.CyanString("BTC Quantity"),
+		color.CyanString(symbol.Base+" Quantity"),
 		color.YellowString("%.6f", newCycle.Quantity),
 	)
 
@@ -208,24 +336,38 @@ func getPercent() float64 {
 	return percent
 }
 
-func getOffset(key string) int {
-	offset := os.Getenv(key)
+// getOffset reads the offset of a price from key, an amount like -200 or a
+// percentage of the price like -1.5%
+func getOffset(key string) (float64, database.OffsetType) {
+	offset := strings.TrimSpace(os.Getenv(key))
 	if offset == "" {
 		color.Red(key + " env variable is required")
 		os.Exit(0)
 	}
-	offsetInt, err := strconv.Atoi(offset)
+
+	offsetType := database.OffsetAbsolute
+	if strings.HasSuffix(offset, "%") {
+		offsetType = database.OffsetPercent
+		offset = strings.TrimSuffix(offset, "%")
+	}
+
+	offsetFloat, err := strconv.ParseFloat(offset, 64)
 	if err != nil {
-		color.Red(key + " env variable must be a number")
+		color.Red(key + " env variable must be a number, or a percentage like -1.5%")
+		os.Exit(0)
+	}
+	if offsetType == database.OffsetPercent && offsetFloat <= -100 {
+		color.Red(key + " must be a percentage greater than -100%")
 		os.Exit(0)
 	}
-	return offsetInt
+	return offsetFloat, offsetType
 }
 
 func notifTelegram(cycle *database.Cycle) {
 	if os.Getenv("TELEGRAM") == "1" {
 		var message = ""
 		message += fmt.Sprintf("ℹ️ New Cycle: %d \n", cycle.Id)
+		message += fmt.Sprintf("🪙 Symbol: %s \n", cycle.Symbol)
 		message += fmt.Sprintf("✨ Quantity: %.6f \n", cycle.Quantity)
 		message += fmt.Sprintf("📉 Buy Price: %.2f \n", cycle.Buy.Price)
 		message += fmt.Sprintf("📈 Sell Price: %.2f \n", cycle.Sell.Price)
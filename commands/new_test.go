@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"main/database"
 	"testing"
 )
 
@@ -16,3 +17,19 @@ func TestCalcAmountUSD(t *testing.T) {
 	amountCycleBTC := CalcAmountBTC(availableUSD, priceBTC)
 	fmt.Println(amountCycleBTC)
 }
+
+func TestGetOffset(t *testing.T) {
+	for value, expected := range map[string]float64{"-200": 99800, "200": 100200, "-1.5%": 98500, "2%": 102000} {
+		t.Setenv("BUY_OFFSET", value)
+		offset, offsetType := getOffset("BUY_OFFSET")
+		if price := database.ApplyOffset(100000, offset, offsetType); price != expected {
+			t.Errorf("BUY_OFFSET=%q: expected %v, got %v", value, expected, price)
+		}
+	}
+
+	t.Setenv("BUY_OFFSET", "-1.5%")
+	offset, offsetType := getOffset("BUY_OFFSET")
+	if offsetType != database.OffsetPercent || database.FormatOffset(offset, offsetType) != "-1.5%" {
+		t.Errorf("expected a -1.5%% offset, got %v %s", offset, offsetType)
+	}
+}
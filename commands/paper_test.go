@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"context"
+	"main/database"
+	"main/exchanges"
+	"main/exchanges/paper"
+	"main/feed"
+	"os"
+	"testing"
+)
+
+// priceSource is a paper price source moved by the test
+type priceSource struct {
+	price float64
+}
+
+func (s *priceSource) Price(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	return s.price, nil
+}
+
+// newPaperTest sets up a paper exchange on a temporary database, priced by
+// the returned source. The working directory is a temporary one too, as
+// the commands log to logs/ in it.
+func newPaperTest(t *testing.T, price float64) (*paper.Client, *priceSource) {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("EXCHANGE", "PAPER")
+	err := database.InitDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	source := &priceSource{price: price}
+	paperClient := &paper.Client{Source: source}
+	client = paperClient
+	priceFeeds = map[string]feed.PriceFeed{"PAPER": feed.NewPoller(paperClient, 0)}
+	lastPrices = map[exchanges.Symbol]float64{}
+	t.Cleanup(func() {
+		client = nil
+		priceFeeds = map[string]feed.PriceFeed{}
+	})
+
+	return paperClient, source
+}
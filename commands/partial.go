@@ -0,0 +1,238 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"main/database"
+	"main/exchanges"
+	"os"
+	"strings"
+	"time"
+)
+
+// What to do with a buy order partially filled for longer than
+// PARTIAL_FILL_TIMEOUT
+type PartialFillPolicy string
+
+const (
+	PartialFillWait   PartialFillPolicy = "WAIT"   // keep the order open
+	PartialFillCancel PartialFillPolicy = "CANCEL" // cancel the remainder and sell what filled
+	PartialFillTopUp  PartialFillPolicy = "TOPUP"  // buy the remainder at the last price
+)
+
+func getPartialFillPolicy() PartialFillPolicy {
+	policy := PartialFillPolicy(strings.ToUpper(os.Getenv("PARTIAL_FILL_POLICY")))
+	switch policy {
+	case "":
+		return PartialFillWait
+	case PartialFillWait, PartialFillCancel, PartialFillTopUp:
+		return policy
+	}
+
+	color.Red("PARTIAL_FILL_POLICY must be WAIT, CANCEL or TOPUP")
+	os.Exit(0)
+	return ""
+}
+
+// 60 minutes by default, a number without unit is in minutes like the
+// AUTO_INTERVAL settings
+func getPartialFillTimeout() time.Duration {
+	if os.Getenv("PARTIAL_FILL_TIMEOUT") == "" {
+		return 60 * time.Minute
+	}
+	return dotenvToDuration("PARTIAL_FILL_TIMEOUT")
+}
+
+// An order is stale when nothing happened to it for PARTIAL_FILL_TIMEOUT
+func isStale(order *exchanges.Order) bool {
+	last := order.UpdatedAt
+	if last == 0 {
+		last = order.CreatedAt
+	}
+	if last == 0 {
+		return false
+	}
+	return time.Since(time.UnixMilli(last)) > getPartialFillTimeout()
+}
+
+// Quantity bought by the cycle so far. A top up replaces the buy order by a
+// smaller one, what the previous orders filled is the difference between
+// the cycle quantity and the current order quantity.
+func buyFilled(cycle *database.Cycle, order *exchanges.Order, executed float64) float64 {
+	carried := cycle.Quantity - order.OrigQty
+	if carried < 0 || order.OrigQty == 0 {
+		carried = 0
+	}
+	return carried + executed
+}
+
+// Saves the executed quantity of a side when it changed
+func recordExecuted(cycle *database.Cycle, field string, current *float64, executed float64) error {
+	if executed == *current {
+		return nil
+	}
+
+	_, err := database.CycleUpdate(cycle.Id, field, executed)
+	if err != nil {
+		return fmt.Errorf("error updating cycle %s: %v", field, err)
+	}
+	*current = executed
+	return nil
+}
+
+// handleUnfilledBuy decides what to do with a buy order that is not filled.
+// It returns true when the cycle must go on and sell what filled.
+func handleUnfilledBuy(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, filters exchanges.SymbolFilters, order *exchanges.Order) (bool, error) {
+	filled := cycle.Buy.ExecutedQty
+
+	// Canceled or expired on the exchange
+	if !order.IsActive() {
+		if filled > 0 {
+			fmt.Printf("%s %s\n",
+				color.YellowString("%d", cycle.Id),
+				color.YellowString("Order Buy %s with %.8f filled, selling it", order.Status, filled),
+			)
+			return true, nil
+		}
+
+		// Nothing to sell, the cycle is forgotten like a canceled one
+		err := database.CycleDeleteById(cycle.Id)
+		if err != nil {
+			return false, fmt.Errorf("error deleting cycle: %v", err)
+		}
+
+		fmt.Printf("%s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.RedString("Order Buy %s on the exchange, nothing filled - cycle deleted", order.Status),
+		)
+		Log(fmt.Sprintf("Cycle %d deleted: buy order %s on the exchange", cycle.Id, order.Status))
+		return false, nil
+	}
+
+	// Nothing filled for too long, or the price went away
+	if order.ExecutedQty == 0 {
+		if action, ok := takeAction(cycle.Id); ok {
+			return applyBuyAction(ctx, cycle, symbol, filters, order, action)
+		}
+
+		reason, err := buyExpiry(ctx, cycle, symbol, order)
+		if err != nil {
+			return false, err
+		}
+		if reason != "" {
+			return expireBuy(ctx, cycle, symbol, order, reason)
+		}
+	}
+
+	if order.ExecutedQty == 0 || !isStale(order) {
+		status := "Order Buy still active -"
+		if order.ExecutedQty > 0 {
+			status = fmt.Sprintf("Order Buy partially filled %.8f/%.8f -", filled, cycle.Quantity)
+		}
+		fmt.Printf("%s %s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.CyanString(status),
+			color.WhiteString("%s", order.Id),
+		)
+		return false, nil
+	}
+
+	policy := getPartialFillPolicy()
+	if policy == PartialFillWait {
+		fmt.Printf("%s %s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.CyanString("Order Buy partially filled %.8f/%.8f, waiting -", filled, cycle.Quantity),
+			color.WhiteString("%s", order.Id),
+		)
+		return false, nil
+	}
+
+	// Dust the exchange would refuse to sell, better wait for more
+	err := filters.Validate(cycle.Sell.Price, filters.RoundQuantity(filled))
+	if err != nil {
+		fmt.Printf("%s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.CyanString("Order Buy partially filled, too little to sell yet: %v", err),
+		)
+		return false, nil
+	}
+
+	canceled, err := cancelOrder(ctx, symbol, order.Id)
+	if err != nil {
+		return false, fmt.Errorf("error canceling partially filled buy: %w", err)
+	}
+
+	// More may have filled before the cancel
+	if canceled.ExecutedQty > order.ExecutedQty {
+		filled = buyFilled(cycle, order, canceled.ExecutedQty)
+		err = recordExecuted(cycle, "buyExecutedQty", &cycle.Buy.ExecutedQty, filled)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if policy == PartialFillTopUp {
+		toppedUp, err := topUpBuy(ctx, cycle, symbol, filters, order.Id, filled)
+		if err != nil || toppedUp {
+			return false, err
+		}
+	}
+
+	fmt.Printf("%s %s\n",
+		color.YellowString("%d", cycle.Id),
+		color.YellowString("Order Buy remainder canceled, selling %.8f", filled),
+	)
+	return true, nil
+}
+
+// Places a buy order for what the canceled one did not fill, at the last
+// price. Returns false when the remainder is too small to be bought.
+func topUpBuy(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, filters exchanges.SymbolFilters, canceledId string, filled float64) (bool, error) {
+	lastPrice, err := getLastPrice(ctx, symbol)
+	if err != nil {
+		return false, err
+	}
+
+	remainder := filters.RoundQuantity(cycle.Quantity - filled)
+	price := filters.RoundPrice(lastPrice)
+	if filters.Validate(price, remainder) != nil {
+		return false, nil
+	}
+
+	// The canceled order leaves the cycle, keep its commission
+	fee, feeAsset, err := orderFee(ctx, symbol, canceledId)
+	if err != nil {
+		return false, err
+	}
+
+	order, err := client.CreateOrder(ctx, symbol, "BUY", filters.FormatPrice(price), filters.FormatQuantity(remainder), "")
+	if err != nil {
+		return false, fmt.Errorf("error creating top up buy order: %w", err)
+	}
+
+	// The buy price becomes the average of both orders
+	quantity := filled + remainder
+	buyPrice := (cycle.Buy.Price*filled + price*remainder) / quantity
+	cycle.Buy.Fee, cycle.Buy.FeeAsset = addFee(cycle.Buy.Fee, cycle.Buy.FeeAsset, fee, feeAsset)
+
+	for field, value := range map[string]interface{}{
+		"buyId":       order.Id,
+		"quantity":    quantity,
+		"buyPrice":    buyPrice,
+		"buyFee":      cycle.Buy.Fee,
+		"buyFeeAsset": cycle.Buy.FeeAsset,
+	} {
+		_, err = database.CycleUpdate(cycle.Id, field, value)
+		if err != nil {
+			return false, fmt.Errorf("error updating cycle %s: %v", field, err)
+		}
+	}
+
+	fmt.Printf("%s %s %s\n",
+		color.YellowString("%d", cycle.Id),
+		color.CyanString("Top up buy Order %.8f at %.2f -", remainder, price),
+		color.WhiteString("%s", order.Id),
+	)
+	return true, nil
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"context"
+	"main/database"
+	"main/exchanges"
+	"testing"
+	"time"
+)
+
+func TestGetPartialFillPolicy(t *testing.T) {
+	for value, expected := range map[string]PartialFillPolicy{
+		"":       PartialFillWait,
+		"cancel": PartialFillCancel,
+		"TOPUP":  PartialFillTopUp,
+	} {
+		t.Setenv("PARTIAL_FILL_POLICY", value)
+		if policy := getPartialFillPolicy(); policy != expected {
+			t.Errorf("%q: expected %s, got %s", value, expected, policy)
+		}
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	t.Setenv("PARTIAL_FILL_TIMEOUT", "30")
+
+	old := time.Now().Add(-time.Hour).UnixMilli()
+	recent := time.Now().Add(-time.Minute).UnixMilli()
+
+	if !isStale(&exchanges.Order{CreatedAt: old}) {
+		t.Error("expected an order created an hour ago to be stale")
+	}
+	if isStale(&exchanges.Order{CreatedAt: old, UpdatedAt: recent}) {
+		t.Error("expected an order updated a minute ago not to be stale")
+	}
+	if isStale(&exchanges.Order{}) {
+		t.Error("expected an order without times not to be stale")
+	}
+}
+
+func TestBuyFilled(t *testing.T) {
+	cycle := &database.Cycle{Quantity: 0.001}
+
+	// First buy order
+	if filled := buyFilled(cycle, &exchanges.Order{OrigQty: 0.001}, 0.0004); filled != 0.0004 {
+		t.Errorf("expected 0.0004, got %v", filled)
+	}
+
+	// Top up order of 0.0006 after 0.0004 filled
+	if filled := buyFilled(cycle, &exchanges.Order{OrigQty: 0.0006}, 0.0006); filled != 0.001 {
+		t.Errorf("expected 0.001, got %v", filled)
+	}
+}
+
+func TestHandleBuyCanceled(t *testing.T) {
+	paperClient, _ := newPaperTest(t, 100000)
+
+	order, err := paperClient.CreateOrder(context.Background(), exchanges.DefaultSymbol, "BUY", "90000", "0.001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := database.CycleNew(&database.Cycle{
+		Exchange: "PAPER",
+		Symbol:   "BTC/USDC",
+		Status:   database.Buy,
+		Quantity: 0.001,
+		Buy:      database.BuyStruct{Price: 90000, ID: order.Id},
+		Sell:     database.SellStruct{Price: 110000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Canceled outside the bot, nothing bought
+	_, err = paperClient.CancelOrder(context.Background(), exchanges.DefaultSymbol, order.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cycle, err := database.CycleGetById(int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = handleBuy(context.Background(), cycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cycles, err := database.CycleList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cycles) != 0 {
+		t.Errorf("expected the cycle deleted, got %+v", cycles)
+	}
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"main/database"
+	"main/exchanges"
+	"time"
+)
+
+// A cycle younger than this may still be waiting for the answer to its buy
+// order, in another run of the bot
+const pendingGrace = time.Minute
+
+// Prefix of the client order ids of this database, drawn once, so the ids of
+// a new database never match the orders of a previous one
+func clientOrderPrefix() (string, error) {
+	prefix, err := database.CfgGet("client_order_prefix")
+	if err != nil || prefix != "" {
+		return prefix, err
+	}
+
+	b := make([]byte, 2)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	prefix = hex.EncodeToString(b)
+
+	return prefix, database.CfgSet("client_order_prefix", prefix)
+}
+
+// clientOrderId returns the client order id of the side order of a cycle,
+// like bs-1a2b-42-buy. Kraken accepts 18 characters at most.
+func clientOrderId(cycleId int, side string) (string, error) {
+	prefix, err := clientOrderPrefix()
+	if err != nil {
+		return "", fmt.Errorf("error getting client order prefix: %v", err)
+	}
+	return fmt.Sprintf("bs-%s-%d-%s", prefix, cycleId, side), nil
+}
+
+// confirmBuy attaches the buy order to a pending cycle
+func confirmBuy(cycleId int, orderId string) error {
+	_, err := database.CycleUpdate(cycleId, "buyId", orderId)
+	if err != nil {
+		return fmt.Errorf("error updating cycle buy id: %v", err)
+	}
+	_, err = database.CycleUpdate(cycleId, "status", database.Buy)
+	if err != nil {
+		return fmt.Errorf("error updating cycle status: %v", err)
+	}
+	return nil
+}
+
+// RecoverPendingCycles resolves the cycles left pending by a crash between
+// their insert and the answer to their buy order. The order is looked up by
+// its client order id: found, the cycle goes on, otherwise it is deleted.
+// It runs once when the bot starts.
+func RecoverPendingCycles(ctx context.Context) error {
+	cycles, err := database.CycleList()
+	if err != nil {
+		return fmt.Errorf("error getting cycles: %v", err)
+	}
+
+	for _, cycle := range cycles {
+		if cycle.Status != database.Pending || time.Since(time.UnixMilli(cycle.CreatedAt)) < pendingGrace {
+			continue
+		}
+
+		err := recoverPendingCycle(ctx, &cycle)
+		if err == nil {
+			continue
+		}
+		if actionFor(err) != skipCycle {
+			return err
+		}
+		color.Red("%d Pending cycle skipped: %v", cycle.Id, err)
+	}
+
+	return nil
+}
+
+func recoverPendingCycle(ctx context.Context, cycle *database.Cycle) error {
+	symbol, err := cycleSymbol(cycle)
+	if err != nil {
+		return err
+	}
+
+	clientId, err := clientOrderId(cycle.Id, "buy")
+	if err != nil {
+		return err
+	}
+
+	order, err := GetClientByExchange(cycle.Exchange).GetOrderByClientId(ctx, symbol, clientId)
+	if errors.Is(err, exchanges.ErrOrderNotFound) {
+		err = database.CycleDeleteById(cycle.Id)
+		if err != nil {
+			return fmt.Errorf("error deleting cycle: %v", err)
+		}
+		color.Yellow("%d Pending cycle deleted, order %s was never placed", cycle.Id, clientId)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error looking for order %s: %w", clientId, err)
+	}
+
+	err = confirmBuy(cycle.Id, order.Id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s %s %s\n",
+		color.YellowString("%d", cycle.Id),
+		color.GreenString("Pending cycle recovered, Order Buy -"),
+		color.WhiteString("%s", order.Id),
+	)
+	return nil
+}
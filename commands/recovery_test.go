@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"context"
+	"main/database"
+	"main/exchanges"
+	"main/exchanges/paper"
+	"testing"
+	"time"
+)
+
+func TestRecoverPendingCycles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	err := database.InitDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	old := time.Now().Add(-time.Hour)
+	for _, createdAt := range []time.Time{old, old, time.Now()} {
+		id, err := database.CycleNew(&database.Cycle{Exchange: "PAPER", Symbol: "BTC/USDC", Status: database.Pending, Quantity: 0.001, CreatedAt: createdAt.UnixMilli()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, int(id))
+	}
+
+	// The first cycle crashed after its order was placed, the second before,
+	// the third may still be waiting for its order
+	clientId, err := clientOrderId(ids[0], "buy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := paper.NewClient().CreateOrder(context.Background(), exchanges.DefaultSymbol, "BUY", "90000", "0.001", clientId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RecoverPendingCycles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cycle, err := database.CycleGetById(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycle.Status != database.Buy || cycle.Buy.ID != order.Id {
+		t.Errorf("expected cycle %d in buy with order %s, got %s %q", ids[0], order.Id, cycle.Status, cycle.Buy.ID)
+	}
+
+	_, err = database.CycleGetById(ids[1])
+	if err == nil {
+		t.Errorf("expected cycle %d without order to be deleted", ids[1])
+	}
+
+	cycle, err = database.CycleGetById(ids[2])
+	if err != nil || cycle.Status != database.Pending {
+		t.Errorf("expected the recent cycle %d left pending, got %+v %v", ids[2], cycle, err)
+	}
+}
+
+func TestClientOrderId(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	err := database.InitDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := clientOrderId(42, "buy")
+	second, _ := clientOrderId(42, "buy")
+	if first != second || len(first) > 18 {
+		t.Errorf("expected the same id of 18 characters at most, got %q and %q", first, second)
+	}
+}
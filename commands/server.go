@@ -1,12 +1,16 @@
 package commands
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/fatih/color"
 	"html/template"
 	"main/database"
+	"main/exchanges"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
@@ -42,7 +46,9 @@ func getAddressServer() string {
 	return "localhost:8080"
 }
 
-func Server() error {
+// Server serves the dashboard until ctx is done. The requests to the
+// exchanges of the pages are canceled with it.
+func Server(ctx context.Context) error {
 	MainMiddleware()
 
 	var address = getAddressServer()
@@ -60,8 +66,18 @@ func Server() error {
 
 	mux.HandleFunc("/api/get-order", getOrder)
 
-	err := http.ListenAndServe(address, mux)
-	if err != nil {
+	server := &http.Server{
+		Addr:        address,
+		Handler:     mux,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("error starting server: %v", err)
 	}
 
@@ -84,33 +100,49 @@ func displayStats(w http.ResponseWriter, r *http.Request) {
 	totalBuy := 0.0
 	totalSell := 0.0
 	totalProfit := 0.0
+	totalNetProfit := 0.0
 
 	for _, cycle := range cycles {
 		//fmt.Printf("%+v\n", cycle)
 		cyclesCount++
-		if cycle.Status == database.Completed {
-			cyclesCompleted++
+		// Stopped cycles realized their loss
+		if cycle.Status == database.Completed || cycle.Status == database.Stopped {
+			if cycle.Status == database.Completed {
+				cyclesCompleted++
+			}
 
 			totalBuy += cycle.Buy.Price * cycle.Quantity
 			totalSell += cycle.Sell.Price * cycle.Quantity
 
 			totalProfit += cycle.CalcProfit()
+			totalNetProfit += cycle.CalcNetProfit()
 		}
 
 	}
 
-	// Compute Balance BTC from USD balance and BTC price
-	balanceBTC := 0.0
+	// Compute base asset balance from quote balance and last price
+	symbol := getSymbol()
+	balanceBase := 0.0
+	lastPrice := 0.0
 	{
+		ctx := r.Context()
 		client := GetClientByExchange()
 		if client != nil {
-			if usd, err2 := client.GetBalanceUSD(); err2 == nil {
-				if price, err3 := client.GetLastPriceBTC(); err3 == nil && price > 0 {
-					balanceBTC = usd / price
+			if latest, err2 := getPriceFeed("").Latest(ctx, symbol); err2 == nil && latest.Value > 0 {
+				lastPrice = latest.Value
+				if quote, err3 := client.GetBalance(ctx, symbol.Quote); err3 == nil {
+					balanceBase = quote / lastPrice
 				}
 			}
 		}
 	}
+
+	grid, err := gridLadder()
+	if err != nil {
+		http.Error(w, "Error getting grid", http.StatusInternalServerError)
+		return
+	}
+
 	tmpl, err := template.ParseFS(templateFS, "misc/template.html")
 	if err != nil {
 		http.Error(w, "Error parsing template", http.StatusInternalServerError)
@@ -124,8 +156,12 @@ func displayStats(w http.ResponseWriter, r *http.Request) {
 		"totalBuy":        totalBuy,
 		"totalSell":       totalSell,
 		"totalProfit":     totalProfit,
-		"balanceBTC":      balanceBTC,
+		"totalNetProfit":  totalNetProfit,
+		"symbol":          symbol,
+		"balanceBase":     balanceBase,
+		"lastPrice":       lastPrice,
 		"page":            page,
+		"grid":            grid,
 	})
 
 	if err != nil {
@@ -145,6 +181,7 @@ func getOrder(w http.ResponseWriter, r *http.Request) {
 	var data struct {
 		OrderID  string `json:"orderId"`
 		Exchange string `json:"exchange"`
+		Symbol   string `json:"symbol"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&data)
@@ -154,14 +191,19 @@ func getOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	symbol, err := exchanges.ParseSymbol(data.Symbol)
+	if err != nil {
+		symbol = exchanges.DefaultSymbol
+	}
+
 	client := GetClientByExchange(data.Exchange)
-	order, err := client.GetOrderById(data.OrderID)
+	order, err := client.GetOrderById(r.Context(), symbol, data.OrderID)
 	if err != nil {
 		http.Error(w, `{"error": "order not found"}`, http.StatusNotFound)
 		return
 	}
 
-	_, err = w.Write(order)
+	err = json.NewEncoder(w).Encode(order)
 	if err != nil {
 		return
 	}
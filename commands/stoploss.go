@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"main/database"
+	"main/exchanges"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// getPriceOffset reads key, either a percentage of a price like "5%" or an
+// amount like "1500". Empty or 0 disables it.
+func getPriceOffset(key string) (value float64, percent bool) {
+	str := strings.TrimSpace(os.Getenv(key))
+	if str == "" {
+		return 0, false
+	}
+
+	percent = strings.HasSuffix(str, "%")
+	value, err := strconv.ParseFloat(strings.TrimSuffix(str, "%"), 64)
+	if err != nil || value < 0 || (percent && value >= 100) {
+		color.Red("%s must be a percentage like 5%% or an offset like 1500", key)
+		os.Exit(0)
+	}
+	return value, percent
+}
+
+// below returns price lowered by the offset read by getPriceOffset
+func below(price, value float64, percent bool) float64 {
+	if percent {
+		return price * (1 - value/100)
+	}
+	return price - value
+}
+
+// getStopLoss reads STOP_LOSS, how far below the buy price a cycle is
+// stopped
+func getStopLoss() (value float64, percent bool) {
+	return getPriceOffset("STOP_LOSS")
+}
+
+// getStopLossSlippage returns how far below the last price the exit order
+// is placed so it fills at once, STOP_LOSS_SLIPPAGE percent, 0.5 by default
+func getStopLossSlippage() float64 {
+	str := os.Getenv("STOP_LOSS_SLIPPAGE")
+	if str == "" {
+		return 0.5
+	}
+
+	slippage, err := strconv.ParseFloat(str, 64)
+	if err != nil || slippage < 0 || slippage >= 100 {
+		color.Red("STOP_LOSS_SLIPPAGE must be a percentage between 0 and 100")
+		os.Exit(0)
+	}
+	return slippage
+}
+
+// stopPrice returns the price at which the cycle is stopped, 0 without
+// stop-loss
+func stopPrice(cycle *database.Cycle) float64 {
+	value, percent := getStopLoss()
+	if value == 0 {
+		return 0
+	}
+	return below(cycle.Buy.Price, value, percent)
+}
+
+// checkStopLoss stops a cycle whose sell order is not filled when the price
+// fell to its stop price: the sell order is canceled and what it did not
+// sell is sold at once. Returns true when the cycle was stopped.
+func checkStopLoss(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, order *exchanges.Order) (bool, error) {
+	stop := stopPrice(cycle)
+	if stop <= 0 {
+		return false, nil
+	}
+
+	lastPrice, err := getLastPrice(ctx, symbol)
+	if err != nil {
+		return false, err
+	}
+	if lastPrice > stop {
+		return false, nil
+	}
+
+	fmt.Printf("%s %s\n",
+		color.YellowString("%d", cycle.Id),
+		color.RedString("Stop-loss hit at %.2f (stop %.2f), canceling the sell order", lastPrice, stop),
+	)
+
+	err = exitSell(ctx, cycle, symbol, order)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// exitSell cancels the sell order when still active and sells at once what
+// it did not sell
+func exitSell(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, order *exchanges.Order) error {
+	canceled := order
+	if order.IsActive() {
+		var err error
+		canceled, err = cancelOrder(ctx, symbol, order.Id)
+		if err != nil {
+			return fmt.Errorf("error canceling sell order to exit: %w", err)
+		}
+	}
+
+	// What the order sold was recorded by handleSell, addSold counts it
+	cycle.Sell.ExecutedQty = 0
+	err := addSold(ctx, cycle, symbol, canceled)
+	if err != nil {
+		return err
+	}
+
+	return placeExit(ctx, cycle, symbol, canceled.OrigQty-canceled.ExecutedQty)
+}
+
+// placeExit sells quantity with a limit below the last price, so it fills
+// like a market order, and leaves the cycle stopping until it does
+func placeExit(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, quantity float64) error {
+	filters, err := getSymbolFilters(ctx, client, cycle.Exchange, symbol)
+	if err != nil {
+		return err
+	}
+
+	lastPrice, err := getLastPrice(ctx, symbol)
+	if err != nil {
+		return err
+	}
+
+	price := filters.RoundPrice(lastPrice * (1 - getStopLossSlippage()/100))
+	quantity = filters.RoundQuantity(quantity)
+
+	order, err := client.CreateOrder(ctx, symbol, "SELL", filters.FormatPrice(price), filters.FormatQuantity(quantity), "")
+	if err != nil {
+		return fmt.Errorf("error creating exit sell order: %w", err)
+	}
+
+	_, err = database.CycleUpdate(cycle.Id, "sellId", order.Id)
+	if err != nil {
+		return fmt.Errorf("error updating cycle sell id: %v", err)
+	}
+	_, err = database.CycleUpdate(cycle.Id, "status", database.Stopping)
+	if err != nil {
+		return fmt.Errorf("error updating cycle status: %v", err)
+	}
+	cycle.Sell.ID = order.Id
+	cycle.Status = database.Stopping
+
+	fmt.Printf("%s %s %s\n",
+		color.YellowString("%d", cycle.Id),
+		color.CyanString("Exit sell Order %.8f at %.2f -", quantity, price),
+		color.WhiteString("%s", order.Id),
+	)
+	return nil
+}
+
+// addSold adds what a sell order sold to the cycle: the sell price becomes
+// the average of the orders and their commissions add up
+func addSold(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, order *exchanges.Order) error {
+	if order.ExecutedQty == 0 {
+		return nil
+	}
+
+	fee, feeAsset, err := orderFee(ctx, symbol, order.Id)
+	if err != nil {
+		return err
+	}
+
+	sold := cycle.Sell.ExecutedQty + order.ExecutedQty
+	cycle.Sell.Price = (cycle.Sell.Price*cycle.Sell.ExecutedQty + fillPrice(order)*order.ExecutedQty) / sold
+	cycle.Sell.ExecutedQty = sold
+	cycle.Sell.Fee, cycle.Sell.FeeAsset = addFee(cycle.Sell.Fee, cycle.Sell.FeeAsset, fee, feeAsset)
+
+	for field, value := range map[string]interface{}{
+		"sellPrice":       cycle.Sell.Price,
+		"sellExecutedQty": cycle.Sell.ExecutedQty,
+		"sellFee":         cycle.Sell.Fee,
+		"sellFeeAsset":    cycle.Sell.FeeAsset,
+	} {
+		_, err := database.CycleUpdate(cycle.Id, field, value)
+		if err != nil {
+			return fmt.Errorf("error updating cycle %s: %v", field, err)
+		}
+	}
+	return nil
+}
+
+// handleStopping follows the exit order of a stopped cycle. An exit the
+// price ran away from is placed again below the new last price.
+func handleStopping(ctx context.Context, cycle *database.Cycle) error {
+	symbol, err := cycleSymbol(cycle)
+	if err != nil {
+		return err
+	}
+
+	order, err := client.GetOrderById(ctx, symbol, cycle.Sell.ID)
+	if err != nil {
+		return fmt.Errorf("error getting order: %w", err)
+	}
+
+	if !order.IsFilled() {
+		lastPrice, err := getLastPrice(ctx, symbol)
+		if err != nil {
+			return err
+		}
+		if order.IsActive() && lastPrice >= order.Price {
+			fmt.Printf("%s %s %s\n",
+				color.YellowString("%d", cycle.Id),
+				color.CyanString("Order Stop-loss sell still active -"),
+				color.WhiteString("%s", order.Id),
+			)
+			return nil
+		}
+
+		if order.IsActive() {
+			order, err = cancelOrder(ctx, symbol, order.Id)
+			if err != nil {
+				return fmt.Errorf("error canceling stop-loss sell order: %w", err)
+			}
+		}
+		err = addSold(ctx, cycle, symbol, order)
+		if err != nil {
+			return err
+		}
+		return placeExit(ctx, cycle, symbol, order.OrigQty-order.ExecutedQty)
+	}
+
+	err = addSold(ctx, cycle, symbol, order)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.CycleUpdate(cycle.Id, "status", database.Stopped)
+	if err != nil {
+		return fmt.Errorf("error updating cycle status: %v", err)
+	}
+
+	fmt.Printf("%s %s %s %s\n",
+		color.YellowString("%d", cycle.Id),
+		color.RedString("Order Stop-loss sell filled - Cycle stopped"),
+		color.RedString("$ %.2f", cycle.CalcNetProfit()),
+		color.BlueString("(net %.2f%%)", cycle.CalcNetPercent()),
+	)
+	Log(fmt.Sprintf("Cycle %d stopped at %.2f, net %.2f", cycle.Id, cycle.Sell.Price, cycle.CalcNetProfit()))
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"main/database"
+	"main/exchanges"
+	"main/exchanges/paper"
+	"testing"
+)
+
+func TestStopPrice(t *testing.T) {
+	cycle := &database.Cycle{Buy: database.BuyStruct{Price: 100000}}
+
+	for value, expected := range map[string]float64{"": 0, "5%": 95000, "1500": 98500} {
+		t.Setenv("STOP_LOSS", value)
+		if stop := stopPrice(cycle); stop != expected {
+			t.Errorf("STOP_LOSS=%q: expected %v, got %v", value, expected, stop)
+		}
+	}
+}
+
+func TestStopLoss(t *testing.T) {
+	t.Setenv("STOP_LOSS", "5%")
+	t.Setenv("STOP_LOSS_SLIPPAGE", "0.5")
+	ctx := context.Background()
+	paperClient, source := newPaperTest(t, 99000)
+
+	// Bought at 100000, selling at 110000
+	buy, err := paperClient.CreateOrder(ctx, exchanges.DefaultSymbol, "BUY", "100000", "0.001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = paperClient.GetLastPrice(ctx, exchanges.DefaultSymbol)
+	sell, err := paperClient.CreateOrder(ctx, exchanges.DefaultSymbol, "SELL", "110000", "0.001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := database.CycleNew(&database.Cycle{
+		Exchange: "PAPER",
+		Symbol:   "BTC/USDC",
+		Status:   database.Sell,
+		Quantity: 0.001,
+		Buy:      database.BuyStruct{Price: 100000, ID: buy.Id},
+		Sell:     database.SellStruct{Price: 110000, ID: sell.Id},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := func() *database.Cycle {
+		cycle, err := database.CycleGetById(int(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lastPrices = map[exchanges.Symbol]float64{}
+		switch cycle.Status {
+		case database.Sell:
+			err = handleSell(ctx, cycle)
+		case database.Stopping:
+			err = handleStopping(ctx, cycle)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		cycle, err = database.CycleGetById(int(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cycle
+	}
+
+	// Above the stop price the sell order waits
+	source.price = 96000
+	if cycle := update(); cycle.Status != database.Sell {
+		t.Fatalf("expected the cycle to keep selling above 95000, got %s", cycle.Status)
+	}
+
+	// Under it, the sell order is replaced by one under the last price
+	source.price = 94000
+	cycle := update()
+	if cycle.Status != database.Stopping || cycle.Sell.ID == sell.Id {
+		t.Fatalf("expected the cycle stopping with a new sell order, got %s %s", cycle.Status, cycle.Sell.ID)
+	}
+	canceled, _ := paperClient.GetOrderById(ctx, exchanges.DefaultSymbol, sell.Id)
+	if canceled.Status != exchanges.OrderCanceled {
+		t.Errorf("expected the limit sell canceled, got %s", canceled.Status)
+	}
+
+	// The exit fills on the next price, then the cycle ends at a loss
+	update()
+	cycle = update()
+	if cycle.Status != database.Stopped {
+		t.Fatalf("expected the cycle stopped, got %s", cycle.Status)
+	}
+	if cycle.Sell.Price != 93530 || cycle.CalcProfit() >= 0 {
+		t.Errorf("expected a loss sold at 93530, got %v and %.2f", cycle.Sell.Price, cycle.CalcProfit())
+	}
+}
+
+// stubCancelClient answers a cancel like KuCoin or Kraken, without the
+// quantities of the order
+type stubCancelClient struct {
+	*paper.Client
+}
+
+func (c *stubCancelClient) CancelOrder(ctx context.Context, symbol exchanges.Symbol, orderID string) (*exchanges.Order, error) {
+	_, err := c.Client.CancelOrder(ctx, symbol, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return &exchanges.Order{Id: orderID, Symbol: symbol, Status: exchanges.OrderCanceled}, nil
+}
+
+func TestStopLossCancelStub(t *testing.T) {
+	t.Setenv("STOP_LOSS", "5%")
+	t.Setenv("STOP_LOSS_SLIPPAGE", "0.5")
+	ctx := context.Background()
+	paperClient, source := newPaperTest(t, 99000)
+	client = &stubCancelClient{paperClient}
+
+	buy, err := paperClient.CreateOrder(ctx, exchanges.DefaultSymbol, "BUY", "100000", "0.001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = paperClient.GetLastPrice(ctx, exchanges.DefaultSymbol)
+	sell, err := paperClient.CreateOrder(ctx, exchanges.DefaultSymbol, "SELL", "110000", "0.001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycle := &database.Cycle{
+		Exchange: "PAPER",
+		Symbol:   "BTC/USDC",
+		Status:   database.Sell,
+		Quantity: 0.001,
+		Buy:      database.BuyStruct{Price: 100000, ID: buy.Id},
+		Sell:     database.SellStruct{Price: 110000, ID: sell.Id},
+	}
+	id, err := database.CycleNew(cycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycle.Id = int(id)
+
+	exitQty := func() float64 {
+		exit, err := paperClient.GetOrderById(ctx, exchanges.DefaultSymbol, cycle.Sell.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return exit.OrigQty
+	}
+
+	// The exit sells what the canceled order did not, read after the cancel
+	source.price = 94000
+	lastPrices = map[exchanges.Symbol]float64{}
+	err = handleSell(ctx, cycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycle.Status != database.Stopping || exitQty() != 0.001 {
+		t.Fatalf("expected an exit of 0.001, got %s %v", cycle.Status, exitQty())
+	}
+
+	// Placed again under a price that ran away, still for all of it
+	source.price = 90000
+	lastPrices = map[exchanges.Symbol]float64{}
+	err = handleStopping(ctx, cycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitQty() != 0.001 {
+		t.Errorf("expected the exit placed again for 0.001, got %v", exitQty())
+	}
+}
+
+func TestStopLossFeeRetry(t *testing.T) {
+	t.Setenv("STOP_LOSS", "5%")
+	ctx := context.Background()
+	paperClient, source := newPaperTest(t, 99000)
+	fees := &feeClient{Client: paperClient}
+	client = fees
+
+	buy, err := paperClient.CreateOrder(ctx, exchanges.DefaultSymbol, "BUY", "100000", "0.001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = paperClient.GetLastPrice(ctx, exchanges.DefaultSymbol)
+	sell, err := paperClient.CreateOrder(ctx, exchanges.DefaultSymbol, "SELL", "110000", "0.001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycle := &database.Cycle{
+		Exchange: "PAPER",
+		Symbol:   "BTC/USDC",
+		Status:   database.Sell,
+		Quantity: 0.001,
+		Buy:      database.BuyStruct{Price: 100000, ID: buy.Id},
+		Sell:     database.SellStruct{Price: 110000, ID: sell.Id},
+	}
+	id, err := database.CycleNew(cycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycle.Id = int(id)
+
+	source.price = 94000
+	err = handleSell(ctx, cycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = paperClient.GetLastPrice(ctx, exchanges.DefaultSymbol)
+
+	// The exit is filled but the cycle waits for its fee
+	fees.err = errors.New("trades unavailable")
+	err = handleStopping(ctx, cycle)
+	if err == nil {
+		t.Error("expected an error while the trades can not be read")
+	}
+	cycle, err = database.CycleGetById(cycle.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycle.Status != database.Stopping || cycle.Sell.ExecutedQty != 0 {
+		t.Fatalf("expected the cycle still stopping with nothing sold, got %s %v", cycle.Status, cycle.Sell.ExecutedQty)
+	}
+
+	fees.err = nil
+	err = handleStopping(ctx, cycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycle, err = database.CycleGetById(cycle.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycle.Status != database.Stopped || cycle.Sell.Fee != 0.1 {
+		t.Errorf("expected the cycle stopped with a 0.1 fee, got %s %v", cycle.Status, cycle.Sell.Fee)
+	}
+}
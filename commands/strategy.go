@@ -0,0 +1,296 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"main/database"
+	"main/exchanges"
+	"main/strategy"
+	"os"
+	"strings"
+)
+
+// errNoEntry is returned by PrepareNewCycle when the strategy waits
+var errNoEntry = errors.New("no entry")
+
+// Instance of each strategy, kept from one update to the next
+var strategies = map[string]strategy.Strategy{}
+
+// Actions of the strategy on the open cycles, decided once per Update and
+// taken by the handlers of the cycles
+var strategyActions = map[int]strategy.Action{}
+
+// getStrategy returns the strategy named by STRATEGY, offset by default
+func getStrategy() (string, strategy.Strategy) {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("STRATEGY")))
+	if name == "" {
+		name = strategy.DefaultName
+	}
+
+	s, ok := strategies[name]
+	if !ok {
+		var err error
+		s, err = strategy.Get(name)
+		if err != nil {
+			color.Red("STRATEGY must be one of %s", strings.Join(strategy.Names(), ", "))
+			os.Exit(0)
+		}
+		strategies[name] = s
+	}
+	return name, s
+}
+
+// entryOffsets returns the offsets of bot.conf from price: BUY_OFFSET and
+// SELL_OFFSET, or multiples of the volatility with VOLATILITY
+func entryOffsets(ctx context.Context, klines strategy.KlineReader, symbol exchanges.Symbol, price float64) (strategy.Offsets, error) {
+	var offsets strategy.Offsets
+
+	method := getVolatilityMethod()
+	if method == "" {
+		offsets.Buy, offsets.BuyType = getOffset("BUY_OFFSET")
+		offsets.Sell, offsets.SellType = getOffset("SELL_OFFSET")
+		offsets.BuyRule = database.FormatOffset(offsets.Buy, offsets.BuyType)
+		offsets.SellRule = database.FormatOffset(offsets.Sell, offsets.SellType)
+		return offsets, nil
+	}
+
+	buyMultiplier := getVolatilityMultiplier("VOLATILITY_BUY_MULTIPLIER", -1)
+	sellMultiplier := getVolatilityMultiplier("VOLATILITY_SELL_MULTIPLIER", 1)
+
+	volatility, err := measureVolatility(ctx, klines, method, symbol, price)
+	if err != nil {
+		return offsets, err
+	}
+	offsets.Volatility = volatility
+
+	offsets.Buy = volatilityOffset(volatility, buyMultiplier, price)
+	offsets.BuyType = database.OffsetAbsolute
+	offsets.Sell = volatilityOffset(volatility, sellMultiplier, price)
+	offsets.SellType = database.OffsetAbsolute
+
+	measure := fmt.Sprintf("%s of %d x %s", method, getVolatilityPeriod(), getVolatilityInterval())
+	offsets.BuyRule = fmt.Sprintf("%v x %s", buyMultiplier, measure)
+	offsets.SellRule = fmt.Sprintf("%v x %s", sellMultiplier, measure)
+	return offsets, nil
+}
+
+// openCycles returns the open cycles of symbol on exchange
+func openCycles(exchange string, symbol exchanges.Symbol) ([]database.Cycle, error) {
+	cycles, err := database.CycleList()
+	if err != nil {
+		return nil, fmt.Errorf("error getting cycles: %v", err)
+	}
+
+	var open []database.Cycle
+	for _, cycle := range cycles {
+		if cycle.IsOpen() && strings.EqualFold(cycle.Exchange, exchange) && cycle.Symbol == symbol.String() {
+			open = append(open, cycle)
+		}
+	}
+	return open, nil
+}
+
+// manageCycles asks the strategy what to do with the open cycles of the
+// configured symbol
+func manageCycles(ctx context.Context) error {
+	strategyActions = map[int]strategy.Action{}
+
+	exchange := getExchange()
+	symbol := getSymbol()
+
+	open, err := openCycles(exchange, symbol)
+	if err != nil || len(open) == 0 {
+		return err
+	}
+
+	price, err := getLastPrice(ctx, symbol)
+	if err != nil {
+		return err
+	}
+	filters, err := getSymbolFilters(ctx, client, exchange, symbol)
+	if err != nil {
+		return err
+	}
+
+	name, s := getStrategy()
+	actions, err := s.Manage(ctx, strategy.Market{
+		Exchange: exchange,
+		Symbol:   symbol,
+		Price:    price,
+		Filters:  filters,
+		Klines:   client,
+	}, open)
+	if err != nil {
+		return fmt.Errorf("error managing cycles with the %s strategy: %w", name, err)
+	}
+
+	for _, action := range actions {
+		fmt.Printf("%s %s\n",
+			color.YellowString("%d", action.CycleId),
+			color.MagentaString("Strategy %s: %s", name, describeAction(action)),
+		)
+		strategyActions[action.CycleId] = action
+	}
+	return nil
+}
+
+func describeAction(action strategy.Action) string {
+	description := string(action.Kind)
+	if action.Kind == strategy.Reprice {
+		description += fmt.Sprintf(" at %.2f", action.Price)
+	}
+	if action.Reason != "" {
+		description += ", " + action.Reason
+	}
+	return description
+}
+
+// takeAction returns the action on the cycle once, so the user stream does
+// not take it again before the next Update
+func takeAction(cycleId int) (strategy.Action, bool) {
+	action, ok := strategyActions[cycleId]
+	delete(strategyActions, cycleId)
+	return action, ok
+}
+
+// applyBuyAction takes the action of the strategy on a buy order with
+// nothing filled. It returns true when some was bought in the meantime, the
+// cycle must then sell it.
+func applyBuyAction(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, filters exchanges.SymbolFilters, order *exchanges.Order, action strategy.Action) (bool, error) {
+	if action.Kind != strategy.Reprice {
+		return expireBuy(ctx, cycle, symbol, order, "strategy "+describeAction(action))
+	}
+
+	price := filters.RoundPrice(action.Price)
+	if price == cycle.Buy.Price {
+		return false, nil
+	}
+
+	canceled, err := cancelOrder(ctx, symbol, order.Id)
+	if err != nil {
+		return false, fmt.Errorf("error canceling buy order to reprice: %w", err)
+	}
+
+	filled := buyFilled(cycle, order, canceled.ExecutedQty)
+	if filled > 0 {
+		err = recordExecuted(cycle, "buyExecutedQty", &cycle.Buy.ExecutedQty, filled)
+		if err != nil {
+			return false, err
+		}
+		fmt.Printf("%s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.YellowString("Order Buy repriced with %.8f filled, selling it", filled),
+		)
+		return true, nil
+	}
+
+	newOrder, err := client.CreateOrder(ctx, symbol, "BUY", filters.FormatPrice(price), filters.FormatQuantity(cycle.Quantity), "")
+	if err != nil {
+		return false, fmt.Errorf("error creating repriced buy order: %w", err)
+	}
+
+	for field, value := range map[string]interface{}{
+		"buyId":    newOrder.Id,
+		"buyPrice": price,
+	} {
+		_, err = database.CycleUpdate(cycle.Id, field, value)
+		if err != nil {
+			return false, fmt.Errorf("error updating cycle %s: %v", field, err)
+		}
+	}
+	cycle.Buy.ID = newOrder.Id
+	cycle.Buy.Price = price
+
+	fmt.Printf("%s %s %s\n",
+		color.YellowString("%d", cycle.Id),
+		color.CyanString("Order Buy repriced at %.2f -", price),
+		color.WhiteString("%s", newOrder.Id),
+	)
+	return false, nil
+}
+
+// applySellAction takes the action of the strategy on an active sell order.
+// It returns true when the order was replaced.
+func applySellAction(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, order *exchanges.Order, action strategy.Action) (bool, error) {
+	switch action.Kind {
+	case strategy.Exit:
+		return true, exitSell(ctx, cycle, symbol, order)
+	case strategy.Reprice:
+		if order.ExecutedQty > 0 {
+			color.Yellow("%d Order Sell partially filled, not repriced", cycle.Id)
+			return false, nil
+		}
+	default:
+		color.Yellow("%d Cycle bought, %s ignored", cycle.Id, action.Kind)
+		return false, nil
+	}
+
+	filters, err := getSymbolFilters(ctx, client, cycle.Exchange, symbol)
+	if err != nil {
+		return false, err
+	}
+	price := filters.RoundPrice(action.Price)
+	if price == cycle.Sell.Price {
+		return false, nil
+	}
+
+	canceled, err := cancelOrder(ctx, symbol, order.Id)
+	if err != nil {
+		return false, fmt.Errorf("error canceling sell order to reprice: %w", err)
+	}
+
+	// Sold in the meantime, the rest leaves at once
+	if canceled.ExecutedQty > 0 {
+		cycle.Sell.ExecutedQty = 0
+		err = addSold(ctx, cycle, symbol, canceled)
+		if err != nil {
+			return false, err
+		}
+		return true, placeExit(ctx, cycle, symbol, canceled.OrigQty-canceled.ExecutedQty)
+	}
+
+	newOrder, err := client.CreateOrder(ctx, symbol, "SELL", filters.FormatPrice(price), filters.FormatQuantity(canceled.OrigQty), "")
+	if err != nil {
+		return false, fmt.Errorf("error creating repriced sell order: %w", err)
+	}
+
+	for field, value := range map[string]interface{}{
+		"sellId":    newOrder.Id,
+		"sellPrice": price,
+	} {
+		_, err = database.CycleUpdate(cycle.Id, field, value)
+		if err != nil {
+			return false, fmt.Errorf("error updating cycle %s: %v", field, err)
+		}
+	}
+	cycle.Sell.ID = newOrder.Id
+	cycle.Sell.Price = price
+
+	fmt.Printf("%s %s %s\n",
+		color.YellowString("%d", cycle.Id),
+		color.CyanString("Order Sell repriced at %.2f -", price),
+		color.WhiteString("%s", newOrder.Id),
+	)
+	return true, nil
+}
+
+// applyTrailingAction takes the action of the strategy on a trailing cycle.
+// It returns true when the cycle left trailing.
+func applyTrailingAction(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, action strategy.Action) (bool, error) {
+	switch action.Kind {
+	case strategy.Exit:
+		return true, placeExit(ctx, cycle, symbol, sellQuantity(cycle, symbol))
+	case strategy.Reprice:
+		_, err := database.CycleUpdate(cycle.Id, "sellPrice", action.Price)
+		if err != nil {
+			return false, fmt.Errorf("error updating cycle sell price: %v", err)
+		}
+		cycle.Sell.Price = action.Price
+		return false, nil
+	default:
+		color.Yellow("%d Cycle bought, %s ignored", cycle.Id, action.Kind)
+		return false, nil
+	}
+}
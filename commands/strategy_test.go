@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"context"
+	"main/database"
+	"main/exchanges"
+	"main/strategy"
+	"testing"
+)
+
+// scriptedStrategy returns the actions set by the test
+type scriptedStrategy struct {
+	actions []strategy.Action
+}
+
+func (s *scriptedStrategy) Entry(ctx context.Context, market strategy.Market, open []database.Cycle) (*strategy.Entry, error) {
+	return nil, nil
+}
+
+func (s *scriptedStrategy) Manage(ctx context.Context, market strategy.Market, open []database.Cycle) ([]strategy.Action, error) {
+	return s.actions, nil
+}
+
+var scripted = &scriptedStrategy{}
+
+func init() {
+	strategy.Register("scripted", func() strategy.Strategy { return scripted })
+}
+
+func TestEntryOffsets(t *testing.T) {
+	t.Setenv("BUY_OFFSET", "-1000")
+	t.Setenv("SELL_OFFSET", "1%")
+	t.Setenv("VOLATILITY", "")
+
+	offsets, err := entryOffsets(context.Background(), nil, exchanges.DefaultSymbol, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offsets.Buy != -1000 || offsets.BuyType != database.OffsetAbsolute || offsets.Sell != 1 || offsets.SellType != database.OffsetPercent {
+		t.Errorf("expected -1000 and 1%%, got %+v", offsets)
+	}
+	if offsets.BuyRule != "-1000" || offsets.SellRule != "1%" {
+		t.Errorf("expected the rules -1000 and 1%%, got %q and %q", offsets.BuyRule, offsets.SellRule)
+	}
+}
+
+func TestStrategyActions(t *testing.T) {
+	t.Setenv("STRATEGY", "scripted")
+	ctx := context.Background()
+	paperClient, _ := newPaperTest(t, 100000)
+
+	newCycle := func(status database.Status, side, price string) int {
+		order, err := paperClient.CreateOrder(ctx, exchanges.DefaultSymbol, side, price, "0.001", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cycle := &database.Cycle{
+			Exchange: "PAPER",
+			Symbol:   "BTC/USDC",
+			Status:   status,
+			Quantity: 0.001,
+			Buy:      database.BuyStruct{Price: 90000},
+			Sell:     database.SellStruct{Price: 110000},
+		}
+		if side == "BUY" {
+			cycle.Buy.ID = order.Id
+		} else {
+			cycle.Sell.ID = order.Id
+		}
+		id, err := database.CycleNew(cycle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(id)
+	}
+
+	// The sell orders need the bitcoins they sell
+	bought, err := paperClient.CreateOrder(ctx, exchanges.DefaultSymbol, "BUY", "100000", "0.002", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = paperClient.GetLastPrice(ctx, exchanges.DefaultSymbol)
+	if order, _ := paperClient.GetOrderById(ctx, exchanges.DefaultSymbol, bought.Id); !order.IsFilled() {
+		t.Fatal("expected the bitcoins bought")
+	}
+
+	repriced := newCycle(database.Buy, "BUY", "90000")
+	canceled := newCycle(database.Buy, "BUY", "90000")
+	exited := newCycle(database.Sell, "SELL", "110000")
+	sold := newCycle(database.Sell, "SELL", "110000")
+	scripted.actions = []strategy.Action{
+		{CycleId: repriced, Kind: strategy.Reprice, Price: 91000},
+		{CycleId: canceled, Kind: strategy.Cancel, Reason: "signal gone"},
+		{CycleId: exited, Kind: strategy.Exit},
+		{CycleId: sold, Kind: strategy.Reprice, Price: 108000},
+	}
+	defer func() { scripted.actions = nil }()
+
+	lastPrices = map[exchanges.Symbol]float64{}
+	err = manageCycles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{repriced, canceled, exited, sold} {
+		cycle, err := database.CycleGetById(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cycle.Status == database.Buy {
+			err = handleBuy(ctx, cycle)
+		} else {
+			err = handleSell(ctx, cycle)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(id int) *database.Cycle {
+		cycle, err := database.CycleGetById(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cycle
+	}
+	if cycle := get(repriced); cycle.Status != database.Buy || cycle.Buy.Price != 91000 {
+		t.Errorf("expected the buy repriced at 91000, got %s %v", cycle.Status, cycle.Buy.Price)
+	}
+	if cycle := get(canceled); cycle.Status != database.Expired {
+		t.Errorf("expected the buy canceled, got %s", cycle.Status)
+	}
+	if cycle := get(exited); cycle.Status != database.Stopping {
+		t.Errorf("expected the cycle exiting, got %s", cycle.Status)
+	}
+	if cycle := get(sold); cycle.Status != database.Sell || cycle.Sell.Price != 108000 {
+		t.Errorf("expected the sell repriced at 108000, got %s %v", cycle.Status, cycle.Sell.Price)
+	}
+
+	// Each action is taken once
+	if len(strategyActions) != 0 {
+		t.Errorf("expected every action taken, %d left", len(strategyActions))
+	}
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"main/database"
+	"main/exchanges"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// OrderStreamer is implemented by the exchanges able to push the changes of
+// orders. StreamOrders blocks until ctx is done or the connection drops.
+type OrderStreamer interface {
+	StreamOrders(ctx context.Context, events chan<- exchanges.OrderEvent) error
+}
+
+// Delay before connecting again after the stream dropped
+var streamRetryDelay = 30 * time.Second
+
+// userStream tells the update loop whether it still has to poll. Polling
+// goes on while the stream is down, and once after it connects again to
+// catch the fills missed in between.
+type userStream struct {
+	connected atomic.Bool
+	stale     atomic.Bool
+}
+
+func newUserStream() *userStream {
+	s := &userStream{}
+	s.stale.Store(true)
+	return s
+}
+
+func (s *userStream) needsPolling() bool {
+	return !s.connected.Load() || s.stale.Load()
+}
+
+// polled is called after a successful Update
+func (s *userStream) polled() {
+	if s.connected.Load() {
+		s.stale.Store(false)
+	}
+}
+
+// run keeps the stream open until ctx is done
+func (s *userStream) run(ctx context.Context, streamer OrderStreamer, events chan<- exchanges.OrderEvent) {
+	for {
+		s.connected.Store(true)
+		err := streamer.StreamOrders(ctx, events)
+		s.connected.Store(false)
+		s.stale.Store(true)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		color.Yellow("User stream dropped, polling orders: %v", err)
+		Log(fmt.Sprintf("User stream dropped: %v", err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(streamRetryDelay):
+		}
+	}
+}
+
+// startUserStream listens to the order changes when USER_STREAM=1 and the
+// exchange has a stream. Each change is handled under lock, like the
+// updates. Returns nil when orders are only polled.
+func startUserStream(ctx context.Context, lock chan struct{}) *userStream {
+	if os.Getenv("USER_STREAM") != "1" {
+		return nil
+	}
+
+	streamer, ok := GetClientByExchange().(OrderStreamer)
+	if !ok {
+		color.Yellow("No user stream on %s, polling orders", os.Getenv("EXCHANGE"))
+		return nil
+	}
+
+	stream := newUserStream()
+	events := make(chan exchanges.OrderEvent, 100)
+	go stream.run(ctx, streamer, events)
+
+	go func() {
+		for {
+			var event exchanges.OrderEvent
+			select {
+			case <-ctx.Done():
+				return
+			case event = <-events:
+			}
+
+			lock <- struct{}{} // acquire
+			err := handleOrderEvent(ctx, event)
+			if err != nil && ctx.Err() == nil {
+				handleAutoError(err)
+			}
+			<-lock // release
+		}
+	}()
+
+	color.Magenta("Listening to order changes on the user stream")
+	return stream
+}
+
+// handleOrderEvent updates the cycle of the order right away
+func handleOrderEvent(ctx context.Context, event exchanges.OrderEvent) error {
+	if event.Status == exchanges.OrderNew {
+		return nil
+	}
+
+	cycles, err := database.CycleList()
+	if err != nil {
+		return fmt.Errorf("error getting cycles: %v", err)
+	}
+
+	for _, cycle := range cycles {
+		if cycle.Status == database.Buy && cycle.Buy.ID == event.OrderId {
+			client = GetClientByExchange(cycle.Exchange)
+			lastPrices = map[exchanges.Symbol]float64{}
+			return handleBuy(ctx, &cycle)
+		}
+		if cycle.Status == database.Sell && cycle.Sell.ID == event.OrderId {
+			client = GetClientByExchange(cycle.Exchange)
+			lastPrices = map[exchanges.Symbol]float64{}
+			return handleSell(ctx, &cycle)
+		}
+		if cycle.Status == database.Stopping && cycle.Sell.ID == event.OrderId {
+			client = GetClientByExchange(cycle.Exchange)
+			lastPrices = map[exchanges.Symbol]float64{}
+			return handleStopping(ctx, &cycle)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"main/exchanges"
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeStreamer stays connected until drop is signaled
+type fakeStreamer struct {
+	connected chan struct{}
+	drop      chan struct{}
+}
+
+func (f *fakeStreamer) StreamOrders(ctx context.Context, events chan<- exchanges.OrderEvent) error {
+	f.connected <- struct{}{}
+	select {
+	case <-f.drop:
+		return exchanges.NetworkError("Fake", errors.New("connection reset"))
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func TestUserStream_FallbackToPolling(t *testing.T) {
+	streamRetryDelay = time.Millisecond
+	defer func() { streamRetryDelay = 30 * time.Second }()
+
+	// The drop is logged to logs/ of the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	streamer := &fakeStreamer{connected: make(chan struct{}), drop: make(chan struct{})}
+	stream := newUserStream()
+	if !stream.needsPolling() {
+		t.Error("expected polling before the stream connects")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.run(ctx, streamer, nil)
+
+	<-streamer.connected
+	if !stream.needsPolling() {
+		t.Error("expected one poll once connected, for the fills missed before")
+	}
+	stream.polled()
+	if stream.needsPolling() {
+		t.Error("expected no polling while connected")
+	}
+
+	streamer.drop <- struct{}{}
+	<-streamer.connected // connected again after streamRetryDelay
+	if !stream.needsPolling() {
+		t.Error("expected polling after the stream dropped")
+	}
+}
+
+func TestUserStream_PolledWhileDown(t *testing.T) {
+	stream := newUserStream()
+	stream.polled()
+	if !stream.needsPolling() {
+		t.Error("expected polling while the stream is down")
+	}
+}
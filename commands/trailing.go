@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"main/database"
+	"main/exchanges"
+	"math"
+)
+
+// getTrailingTakeProfit reads TRAILING_TAKE_PROFIT, how far the price falls
+// back from its high above the sell price before a cycle sells. Empty or 0
+// sells with a limit order at the sell price.
+func getTrailingTakeProfit() (value float64, percent bool) {
+	return getPriceOffset("TRAILING_TAKE_PROFIT")
+}
+
+// trailingStop returns the price at which a trailing cycle sells, never
+// below its sell price. 0 until the price reached the sell price.
+func trailingStop(cycle *database.Cycle) float64 {
+	if cycle.Sell.TrailHigh == 0 {
+		return 0
+	}
+
+	value, percent := getTrailingTakeProfit()
+	return math.Max(below(cycle.Sell.TrailHigh, value, percent), cycle.Sell.Price)
+}
+
+// handleTrailing follows the high of a bought cycle above its sell price,
+// and sells once the price falls back to its trailing stop. The sell order
+// is then handled like any other.
+func handleTrailing(ctx context.Context, cycle *database.Cycle) error {
+	symbol, err := cycleSymbol(cycle)
+	if err != nil {
+		return err
+	}
+
+	lastPrice, err := getLastPrice(ctx, symbol)
+	if err != nil {
+		return err
+	}
+
+	if stop := stopPrice(cycle); stop > 0 && lastPrice <= stop {
+		fmt.Printf("%s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.RedString("Stop-loss hit at %.2f (stop %.2f)", lastPrice, stop),
+		)
+		return placeExit(ctx, cycle, symbol, sellQuantity(cycle, symbol))
+	}
+
+	if action, ok := takeAction(cycle.Id); ok {
+		exited, err := applyTrailingAction(ctx, cycle, symbol, action)
+		if err != nil || exited {
+			return err
+		}
+	}
+
+	if lastPrice >= cycle.Sell.Price && lastPrice > cycle.Sell.TrailHigh {
+		_, err = database.CycleUpdate(cycle.Id, "trailHigh", lastPrice)
+		if err != nil {
+			return fmt.Errorf("error updating cycle trail high: %v", err)
+		}
+		cycle.Sell.TrailHigh = lastPrice
+	}
+
+	stop := trailingStop(cycle)
+	if stop == 0 || lastPrice > stop {
+		status := fmt.Sprintf("Trailing, waiting for %.2f", cycle.Sell.Price)
+		if stop > 0 {
+			status = fmt.Sprintf("Trailing, high %.2f, sells at %.2f", cycle.Sell.TrailHigh, stop)
+		}
+		fmt.Printf("%s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.CyanString(status),
+		)
+		return nil
+	}
+
+	return placeTrailingSell(ctx, cycle, symbol, lastPrice)
+}
+
+// placeTrailingSell sells at the last price, or at the sell price when the
+// price fell under it
+func placeTrailingSell(ctx context.Context, cycle *database.Cycle, symbol exchanges.Symbol, lastPrice float64) error {
+	filters, err := getSymbolFilters(ctx, client, cycle.Exchange, symbol)
+	if err != nil {
+		return err
+	}
+
+	price := filters.RoundPrice(math.Max(lastPrice, cycle.Sell.Price))
+	quantity := filters.RoundQuantity(sellQuantity(cycle, symbol))
+
+	order, err := client.CreateOrder(ctx, symbol, "SELL", filters.FormatPrice(price), filters.FormatQuantity(quantity), "")
+	if err != nil {
+		return fmt.Errorf("error creating sell order: %w", err)
+	}
+
+	_, err = database.CycleUpdate(cycle.Id, "sellId", order.Id)
+	if err != nil {
+		return fmt.Errorf("error updating cycle sell id: %v", err)
+	}
+	_, err = database.CycleUpdate(cycle.Id, "status", database.Sell)
+	if err != nil {
+		return fmt.Errorf("error updating cycle status: %v", err)
+	}
+
+	fmt.Printf("%s %s %s\n",
+		color.YellowString("%d", cycle.Id),
+		color.CyanString("Trailing high %.2f left, new sell Order %.8f at %.2f -", cycle.Sell.TrailHigh, quantity, price),
+		color.WhiteString("%s", order.Id),
+	)
+	return nil
+}
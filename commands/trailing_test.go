@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"context"
+	"main/database"
+	"main/exchanges"
+	"testing"
+)
+
+func TestTrailingStop(t *testing.T) {
+	cycle := &database.Cycle{Sell: database.SellStruct{Price: 110000}}
+
+	t.Setenv("TRAILING_TAKE_PROFIT", "1%")
+	if stop := trailingStop(cycle); stop != 0 {
+		t.Errorf("expected no stop before the sell price is reached, got %v", stop)
+	}
+
+	for value, expected := range map[string]float64{"1%": 113850, "300": 114700, "10%": 110000} {
+		t.Setenv("TRAILING_TAKE_PROFIT", value)
+		cycle.Sell.TrailHigh = 115000
+		if stop := trailingStop(cycle); stop != expected {
+			t.Errorf("TRAILING_TAKE_PROFIT=%q: expected %v, got %v", value, expected, stop)
+		}
+	}
+}
+
+func TestTrailingTakeProfit(t *testing.T) {
+	t.Setenv("TRAILING_TAKE_PROFIT", "1%")
+	ctx := context.Background()
+	paperClient, source := newPaperTest(t, 99000)
+
+	// Bought at 100000, selling above 110000
+	buy, err := paperClient.CreateOrder(ctx, exchanges.DefaultSymbol, "BUY", "100000", "0.001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = paperClient.GetLastPrice(ctx, exchanges.DefaultSymbol)
+	id, err := database.CycleNew(&database.Cycle{
+		Exchange: "PAPER",
+		Symbol:   "BTC/USDC",
+		Status:   database.Buy,
+		Quantity: 0.001,
+		Buy:      database.BuyStruct{Price: 100000, ID: buy.Id},
+		Sell:     database.SellStruct{Price: 110000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(price float64) *database.Cycle {
+		source.price = price
+		cycle, err := database.CycleGetById(int(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Like Update, which matches the paper orders first
+		lastPrices = map[exchanges.Symbol]float64{}
+		_, err = getLastPrice(ctx, exchanges.DefaultSymbol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch cycle.Status {
+		case database.Buy:
+			err = handleBuy(ctx, cycle)
+		case database.Trailing:
+			err = handleTrailing(ctx, cycle)
+		case database.Sell:
+			err = handleSell(ctx, cycle)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		cycle, err = database.CycleGetById(int(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cycle
+	}
+
+	// The filled buy places no sell order yet
+	cycle := update(105000)
+	if cycle.Status != database.Trailing || cycle.Sell.ID != "" {
+		t.Fatalf("expected the cycle trailing without sell order, got %s %q", cycle.Status, cycle.Sell.ID)
+	}
+	if cycle = update(105000); cycle.Sell.TrailHigh != 0 {
+		t.Errorf("expected no high under the sell price, got %v", cycle.Sell.TrailHigh)
+	}
+
+	// Above the sell price the high is followed, and survives a restart as
+	// each update reads the cycle again
+	update(112000)
+	update(115000)
+	cycle = update(114000)
+	if cycle.Status != database.Trailing || cycle.Sell.TrailHigh != 115000 {
+		t.Fatalf("expected the cycle trailing from 115000, got %s %v", cycle.Status, cycle.Sell.TrailHigh)
+	}
+
+	// 1% under the high it sells at the last price
+	cycle = update(113500)
+	if cycle.Status != database.Sell || cycle.Sell.ID == "" {
+		t.Fatalf("expected the cycle selling, got %s %q", cycle.Status, cycle.Sell.ID)
+	}
+	cycle = update(113500)
+	if cycle.Status != database.Completed || cycle.Sell.Price != 113500 {
+		t.Errorf("expected the cycle completed at 113500, got %s %v", cycle.Status, cycle.Sell.Price)
+	}
+}
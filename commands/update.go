@@ -1,26 +1,67 @@
 package commands
 
 import (
+	"context"
 	"fmt"
-	"github.com/buger/jsonparser"
 	"github.com/fatih/color"
-	"log"
 	"main/database"
+	"main/exchanges"
 	"main/tools"
-	"strconv"
 )
 
 var client ExchangeClient = nil
-var lastPrice float64 = 0.0
 
-func Update() error {
+// Last price of each symbol, read from the price feed once per Update
+var lastPrices = map[exchanges.Symbol]float64{}
+
+func getLastPrice(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	price, ok := lastPrices[symbol]
+	if !ok {
+		latest, err := getPriceFeed("").Latest(ctx, symbol)
+		if err != nil {
+			return 0, err
+		}
+		price = latest.Value
+		lastPrices[symbol] = price
+		fmt.Printf("Last price %s: %v\n", symbol, price)
+	}
+	return price, nil
+}
+
+// cancelOrder cancels an order and reads it again, some exchanges answer a
+// cancel without its quantities
+func cancelOrder(ctx context.Context, symbol exchanges.Symbol, id string) (*exchanges.Order, error) {
+	_, err := client.CancelOrder(ctx, symbol, id)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := client.GetOrderById(ctx, symbol, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting canceled order: %w", err)
+	}
+	return order, nil
+}
+
+func Update(ctx context.Context) error {
 	MainMiddleware()
 
 	client = GetClientByExchange()
-	client.CheckConnection()
+	err := client.CheckConnection(ctx)
+	if err != nil {
+		return err
+	}
+
+	lastPrices = map[exchanges.Symbol]float64{}
+	_, err = getLastPrice(ctx, getSymbol())
+	if err != nil {
+		return err
+	}
 
-	lastPrice, _ = client.GetLastPriceBTC()
-	fmt.Println("Last price:", lastPrice)
+	err = manageCycles(ctx)
+	if err != nil {
+		return err
+	}
 
 	cycles, err := database.CycleList()
 	if err != nil {
@@ -28,131 +69,234 @@ func Update() error {
 	}
 
 	for _, cycle := range cycles {
+		var err error
 		if cycle.Status == "buy" {
-			err := handleBuy(&cycle)
+			err = handleBuy(ctx, &cycle)
 			if err != nil {
-				return fmt.Errorf("error handling buy: %v", err)
+				err = fmt.Errorf("error handling buy: %w", err)
 			}
 		} else if cycle.Status == "sell" {
-			err := handleSell(&cycle)
+			err = handleSell(ctx, &cycle)
 			if err != nil {
-				return fmt.Errorf("error handling sell: %v", err)
+				err = fmt.Errorf("error handling sell: %w", err)
 			}
+		} else if cycle.Status == database.Trailing {
+			err = handleTrailing(ctx, &cycle)
+			if err != nil {
+				err = fmt.Errorf("error handling trailing: %w", err)
+			}
+		} else if cycle.Status == database.Stopping {
+			err = handleStopping(ctx, &cycle)
+			if err != nil {
+				err = fmt.Errorf("error handling stop-loss: %w", err)
+			}
+		}
+		if err == nil {
+			continue
+		}
+
+		// The other cycles would fail the same way
+		if actionFor(err) != skipCycle {
+			return err
 		}
+		color.Red("%d Cycle skipped: %v", cycle.Id, err)
+		Log(fmt.Sprintf("Cycle %d skipped: %v", cycle.Id, err))
 	}
 
 	return nil
 }
 
-func handleBuy(cycle *database.Cycle) error {
+func handleBuy(ctx context.Context, cycle *database.Cycle) error {
 	buyOrderId := cycle.Buy.ID
 
-	order, err := client.GetOrderById(buyOrderId)
+	symbol, err := cycleSymbol(cycle)
 	if err != nil {
-		return fmt.Errorf("error getting order: %v", err)
+		return err
 	}
 
-	isFilled, err := client.IsFilled(string(order))
+	order, err := client.GetOrderById(ctx, symbol, buyOrderId)
 	if err != nil {
-		return fmt.Errorf("error checking order: %v", err)
+		return fmt.Errorf("error getting order: %w", err)
 	}
 
-	if !isFilled {
-		fmt.Printf("%s %s %s\n",
-			color.YellowString("%d", cycle.Id),
-			color.CyanString("Order Buy still active -"),
-			color.WhiteString("%s", buyOrderId),
-		)
-
-		return nil
+	filters, err := getSymbolFilters(ctx, client, cycle.Exchange, symbol)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("%s %s\n",
-		color.YellowString("%d", cycle.Id),
-		color.GreenString("Order Buy filled"),
-	)
-
-	sellPrice := cycle.Sell.Price
+	err = recordExecuted(cycle, "buyExecutedQty", &cycle.Buy.ExecutedQty, buyFilled(cycle, order, order.ExecutedQty))
+	if err != nil {
+		return err
+	}
 
-	if lastPrice > cycle.Sell.Price {
-		upOffset := 200.0
-		newSellPrice := cycle.Sell.Price + upOffset
-		sellPrice = newSellPrice
-		fmt.Println("New sell price: ", newSellPrice)
+	if !order.IsFilled() {
+		sell, err := handleUnfilledBuy(ctx, cycle, symbol, filters, order)
+		if err != nil || !sell {
+			return err
+		}
+	} else {
+		fmt.Printf("%s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.GreenString("Order Buy filled"),
+		)
+	}
 
-		_, err := database.CycleUpdate(cycle.Id, "sellPrice", newSellPrice)
+	// Sell what was actually bought
+	if cycle.Buy.ExecutedQty > 0 && cycle.Buy.ExecutedQty != cycle.Quantity {
+		_, err = database.CycleUpdate(cycle.Id, "quantity", cycle.Buy.ExecutedQty)
 		if err != nil {
-			log.Fatal(err)
+			return fmt.Errorf("error updating cycle quantity: %v", err)
 		}
-		fmt.Println("New sell price updated: ")
+		cycle.Quantity = cycle.Buy.ExecutedQty
 	}
 
-	quantity := cycle.Quantity
-	quantityStr := strconv.FormatFloat(quantity, 'f', 6, 64)
-	sellPriceStr := strconv.FormatFloat(sellPrice, 'f', 6, 64)
-
-	bytes, err := client.CreateOrder("SELL", sellPriceStr, quantityStr)
-	if err != nil {
-		return fmt.Errorf("error creating sell order: %v", err)
+	// Real buy price, unless earlier orders replaced by a top up filled too
+	buyPrice := cycle.Buy.Price
+	if order.ExecutedQty == cycle.Quantity {
+		buyPrice = fillPrice(order)
 	}
 
-	orderId, _, _, err := jsonparser.Get(bytes, "orderId")
+	// Commissions of the buy, added to those of the orders replaced by a top
+	// up. They are saved with the sell order, so a failed sell does not count
+	// them twice.
+	fee, feeAsset, err := orderFee(ctx, symbol, buyOrderId)
 	if err != nil {
-		log.Printf("Cycle id %d", cycle.Id)
-		log.Printf("Failed to parse orderId: %v", err)
-		log.Fatal(err)
+		return err
 	}
+	cycle.Buy.Fee, cycle.Buy.FeeAsset = addFee(cycle.Buy.Fee, cycle.Buy.FeeAsset, fee, feeAsset)
 
-	fmt.Printf("%s %s %s\n",
-		color.YellowString("%d", cycle.Id),
-		color.CyanString("New sell Order -"),
-		color.WhiteString("%s", string(bytes)),
-	)
+	status := database.Sell
+	if retrace, _ := getTrailingTakeProfit(); retrace > 0 {
+		// The sell order waits for the price to fall back from its high
+		status = database.Trailing
+		fmt.Printf("%s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.CyanString("Trailing above %.2f", cycle.Sell.Price),
+		)
+	} else {
+		quantityStr := filters.FormatQuantity(sellQuantity(cycle, symbol))
+		sellPriceStr := filters.FormatPrice(cycle.Sell.Price)
+
+		sellOrder, err := client.CreateOrder(ctx, symbol, "SELL", sellPriceStr, quantityStr, "")
+		if err != nil {
+			return fmt.Errorf("error creating sell order: %w", err)
+		}
 
-	_, err = database.CycleUpdate(cycle.Id, "status", database.Sell)
+		fmt.Printf("%s %s %s\n",
+			color.YellowString("%d", cycle.Id),
+			color.CyanString("New sell Order -"),
+			color.WhiteString("%s", sellOrder.Id),
+		)
+
+		_, err = database.CycleUpdate(cycle.Id, "sellId", sellOrder.Id)
+		if err != nil {
+			return fmt.Errorf("error updating cycle sell id: %v", err)
+		}
+	}
+
+	_, err = database.CycleUpdate(cycle.Id, "status", status)
 	if err != nil {
 		return fmt.Errorf("error updating cycle status: %v", err)
 	}
-	_, err = database.CycleUpdate(cycle.Id, "sellId", string(orderId))
-	if err != nil {
-		return fmt.Errorf("error updating cycle sell id: %v", err)
+	for field, value := range map[string]interface{}{
+		"buyPrice":    buyPrice,
+		"buyFee":      cycle.Buy.Fee,
+		"buyFeeAsset": cycle.Buy.FeeAsset,
+	} {
+		_, err = database.CycleUpdate(cycle.Id, field, value)
+		if err != nil {
+			return fmt.Errorf("error updating cycle %s: %v", field, err)
+		}
 	}
 
 	return nil
 }
 
-func handleSell(cycle *database.Cycle) error {
+// sellQuantity is what a bought cycle has to sell: exchanges taking the
+// commission in the base asset leave less
+func sellQuantity(cycle *database.Cycle, symbol exchanges.Symbol) float64 {
+	if cycle.Buy.FeeAsset == symbol.Base {
+		return cycle.Quantity - cycle.Buy.Fee
+	}
+	return cycle.Quantity
+}
+
+func handleSell(ctx context.Context, cycle *database.Cycle) error {
 	sellOrderId := cycle.Sell.ID
-	order, err := client.GetOrderById(sellOrderId)
+
+	symbol, err := cycleSymbol(cycle)
 	if err != nil {
-		return fmt.Errorf("error getting order: %v", err)
+		return err
 	}
 
-	isFilled, err := client.IsFilled(string(order))
+	order, err := client.GetOrderById(ctx, symbol, sellOrderId)
 	if err != nil {
-		return fmt.Errorf("error checking order: %v", err)
+		return fmt.Errorf("error getting order: %w", err)
 	}
 
-	if !isFilled {
+	err = recordExecuted(cycle, "sellExecutedQty", &cycle.Sell.ExecutedQty, order.ExecutedQty)
+	if err != nil {
+		return err
+	}
+
+	if !order.IsFilled() {
+		// Not active, the order may be one the stop-loss canceled before
+		// failing to place its exit
+		stopped, err := checkStopLoss(ctx, cycle, symbol, order)
+		if err != nil || stopped {
+			return err
+		}
+
+		if action, ok := takeAction(cycle.Id); ok && order.IsActive() {
+			replaced, err := applySellAction(ctx, cycle, symbol, order, action)
+			if err != nil || replaced {
+				return err
+			}
+		}
+
+		status := "Order Sell still active -"
+		if !order.IsActive() {
+			status = fmt.Sprintf("Order Sell %s on the exchange -", order.Status)
+		} else if order.ExecutedQty > 0 {
+			status = fmt.Sprintf("Order Sell partially filled %.8f/%.8f -", order.ExecutedQty, order.OrigQty)
+		}
 		fmt.Printf("%s %s %s\n",
 			color.YellowString("%d", cycle.Id),
-			color.CyanString("Order Sell still active -"),
+			color.CyanString(status),
 			color.WhiteString("%s", sellOrderId),
 		)
 
 		return nil
 	}
 
+	fee, feeAsset, err := orderFee(ctx, symbol, sellOrderId)
+	if err != nil {
+		return err
+	}
+	cycle.Sell.Price = fillPrice(order)
+	cycle.Sell.Fee, cycle.Sell.FeeAsset = fee, feeAsset
+	for field, value := range map[string]interface{}{
+		"sellPrice":    cycle.Sell.Price,
+		"sellFee":      cycle.Sell.Fee,
+		"sellFeeAsset": cycle.Sell.FeeAsset,
+	} {
+		_, err = database.CycleUpdate(cycle.Id, field, value)
+		if err != nil {
+			return fmt.Errorf("error updating cycle %s: %v", field, err)
+		}
+	}
+
 	_, err = database.CycleUpdate(cycle.Id, "status", database.Completed)
 	if err != nil {
 		return fmt.Errorf("error updating cycle status: %v", err)
 	}
 
-	fmt.Printf("%s %s %s %s\n",
+	fmt.Printf("%s %s %s %s %s\n",
 		color.YellowString("%d", cycle.Id),
 		color.GreenString("Order Sell filled - "),
 		color.GreenString("Cycle successfully completed"),
 		color.BlueString("%.2f%%", cycle.CalcPercent()),
+		color.BlueString("(net %.2f%%)", cycle.CalcNetPercent()),
 	)
 
 	notifTelegram2(cycle)
@@ -163,8 +307,11 @@ func handleSell(cycle *database.Cycle) error {
 func notifTelegram2(cycle *database.Cycle) {
 	var message = ""
 	message += fmt.Sprintf("✅ Cycle %d completed \n", cycle.Id)
+	message += fmt.Sprintf("🪙 Symbol: %s \n", cycle.Symbol)
 	message += fmt.Sprintf("📉 Buy Price: %.2f \n", cycle.Buy.Price)
 	message += fmt.Sprintf("📈 Sell Price: %.2f \n", cycle.Sell.Price)
 	message += fmt.Sprintf("💰 Gain: $ %.2f \n", cycle.CalcProfit())
+	message += fmt.Sprintf("🧾 Fees: $ %.2f \n", cycle.Fees())
+	message += fmt.Sprintf("💵 Net Gain: $ %.2f \n", cycle.CalcNetProfit())
 	tools.Telegram(message)
 }
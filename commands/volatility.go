@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"main/exchanges"
+	"main/indicators"
+	"main/strategy"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getVolatilityMethod reads VOLATILITY, ATR or STDDEV to compute the offsets
+// of new cycles from the recent volatility. Empty keeps BUY_OFFSET and
+// SELL_OFFSET.
+func getVolatilityMethod() string {
+	method := strings.ToUpper(strings.TrimSpace(os.Getenv("VOLATILITY")))
+	if method != "" && method != "ATR" && method != "STDDEV" {
+		color.Red("VOLATILITY must be ATR, STDDEV or empty")
+		os.Exit(0)
+	}
+	return method
+}
+
+// getVolatilityInterval reads VOLATILITY_INTERVAL, the length of the klines
+// like 1h, 1h by default
+func getVolatilityInterval() time.Duration {
+	str := os.Getenv("VOLATILITY_INTERVAL")
+	if str == "" {
+		return time.Hour
+	}
+
+	interval, err := time.ParseDuration(str)
+	if err != nil || interval <= 0 {
+		color.Red("VOLATILITY_INTERVAL must be a duration like 15m, 1h or 24h")
+		os.Exit(0)
+	}
+	return interval
+}
+
+// getVolatilityPeriod reads VOLATILITY_PERIOD, the number of klines the
+// volatility is measured on, 14 by default
+func getVolatilityPeriod() int {
+	str := os.Getenv("VOLATILITY_PERIOD")
+	if str == "" {
+		return 14
+	}
+
+	period, err := strconv.Atoi(str)
+	if err != nil || period < 2 || period > 500 {
+		color.Red("VOLATILITY_PERIOD must be a number of klines between 2 and 500")
+		os.Exit(0)
+	}
+	return period
+}
+
+// getVolatilityMultiplier reads how many times the volatility an offset is,
+// negative below the price
+func getVolatilityMultiplier(key string, fallback float64) float64 {
+	str := os.Getenv(key)
+	if str == "" {
+		return fallback
+	}
+
+	multiplier, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		color.Red(key + " env variable must be a number")
+		os.Exit(0)
+	}
+	return multiplier
+}
+
+// measureVolatility returns the volatility of symbol over the last
+// VOLATILITY_PERIOD klines, in the quote asset: the ATR, or the standard
+// deviation of the returns applied to price
+func measureVolatility(ctx context.Context, client strategy.KlineReader, method string, symbol exchanges.Symbol, price float64) (float64, error) {
+	interval, period := getVolatilityInterval(), getVolatilityPeriod()
+
+	// One more kline for the first return or true range
+	klines, err := client.GetKlines(ctx, symbol, interval, period+1)
+	if err != nil {
+		return 0, fmt.Errorf("error getting klines: %w", err)
+	}
+	if len(klines) < 2 {
+		return 0, fmt.Errorf("not enough %s klines of %s to measure the volatility", symbol, interval)
+	}
+
+	if method == "ATR" {
+		return indicators.ATR(klines), nil
+	}
+	return indicators.ReturnsStdDev(klines) * price, nil
+}
+
+// volatilityOffset returns multiplier times the volatility, its size kept
+// between VOLATILITY_MIN_OFFSET and VOLATILITY_MAX_OFFSET
+func volatilityOffset(volatility, multiplier, price float64) float64 {
+	size := math.Abs(multiplier * volatility)
+
+	if value, percent := getPriceOffset("VOLATILITY_MIN_OFFSET"); value > 0 {
+		size = math.Max(size, price-below(price, value, percent))
+	}
+	if value, percent := getPriceOffset("VOLATILITY_MAX_OFFSET"); value > 0 {
+		size = math.Min(size, price-below(price, value, percent))
+	}
+
+	return math.Copysign(size, multiplier)
+}
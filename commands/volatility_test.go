@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"context"
+	"main/exchanges"
+	"main/exchanges/paper"
+	"testing"
+	"time"
+)
+
+// klineSource is a paper price source with past klines
+type klineSource struct {
+	priceSource
+	klines []exchanges.Kline
+}
+
+func (s *klineSource) GetKlines(ctx context.Context, symbol exchanges.Symbol, interval time.Duration, limit int) ([]exchanges.Kline, error) {
+	return s.klines[max(len(s.klines)-limit, 0):], nil
+}
+
+func TestMeasureVolatility(t *testing.T) {
+	t.Setenv("VOLATILITY_PERIOD", "2")
+
+	source := &klineSource{klines: []exchanges.Kline{
+		{High: 1000, Low: 900, Close: 950}, // out of the period
+		{High: 105, Low: 95, Close: 100},
+		{High: 112, Low: 104, Close: 110},
+		{High: 111, Low: 98, Close: 99},
+	}}
+	client := &paper.Client{Source: source}
+
+	atr, err := measureVolatility(context.Background(), client, "ATR", exchanges.DefaultSymbol, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atr != 12.5 {
+		t.Errorf("expected an ATR of 12.5, got %v", atr)
+	}
+
+	// Returns of 10% and -10%
+	stdDev, err := measureVolatility(context.Background(), client, "STDDEV", exchanges.DefaultSymbol, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdDev < 9.99 || stdDev > 10.01 {
+		t.Errorf("expected a standard deviation of 10 at 100, got %v", stdDev)
+	}
+
+	_, err = measureVolatility(context.Background(), &paper.Client{Source: &priceSource{}}, "ATR", exchanges.DefaultSymbol, 100)
+	if err == nil {
+		t.Error("expected an error without klines")
+	}
+}
+
+func TestVolatilityOffset(t *testing.T) {
+	if offset := volatilityOffset(800, -1.5, 100000); offset != -1200 {
+		t.Errorf("expected -1200, got %v", offset)
+	}
+
+	t.Setenv("VOLATILITY_MIN_OFFSET", "0.5%")
+	t.Setenv("VOLATILITY_MAX_OFFSET", "1000")
+	for multiplier, expected := range map[float64]float64{-0.1: -500, 0.1: 500, 1: 800, -2: -1000} {
+		if offset := volatilityOffset(800, multiplier, 100000); offset != expected {
+			t.Errorf("multiplier %v: expected %v, got %v", multiplier, expected, offset)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// CfgGet returns the value stored under key, or "" when the key does not exist
+func CfgGet(key string) (string, error) {
+	db, err := GetDB()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = db.Close() }()
+
+	var value string
+	err = db.QueryRow("SELECT value FROM cfg_items WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+func CfgSet(key string, value string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	for attempt := 0; attempt < 5; attempt++ {
+		_, err = db.Exec("INSERT INTO cfg_items (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value", key, value)
+		if err == nil {
+			return nil
+		}
+		if strings.Contains(err.Error(), "SQLITE_BUSY") || strings.Contains(err.Error(), "database is locked") {
+			time.Sleep(time.Duration(100*(attempt+1)) * time.Millisecond)
+			continue
+		}
+		return err
+	}
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -11,21 +12,61 @@ import (
 type Status string
 
 const (
+	Pending   Status = "pending" // inserted, the buy order is not confirmed yet
 	Buy       Status = "buy"
 	Sell      Status = "sell"
 	Completed Status = "completed"
+	Stopping  Status = "stopping" // the stop-loss sell order is not filled yet
+	Stopped   Status = "stopped"  // sold by the stop-loss, at a loss
+	Trailing  Status = "trailing" // bought, the sell waits for the price to fall back from its high
+	Expired   Status = "expired"  // the buy order was canceled unfilled, too old or too far from the price
 )
 
+// OffsetType tells whether the offset of a price is an amount of the quote
+// asset or a percentage of the price
+type OffsetType string
+
+const (
+	OffsetAbsolute OffsetType = "absolute"
+	OffsetPercent  OffsetType = "percent"
+)
+
+// FormatOffset returns an offset as configured, like "-200" or "-1.5%"
+func FormatOffset(offset float64, offsetType OffsetType) string {
+	str := strconv.FormatFloat(offset, 'f', -1, 64)
+	if offsetType == OffsetPercent {
+		return str + "%"
+	}
+	return str
+}
+
+// ApplyOffset returns price moved by the offset
+func ApplyOffset(price, offset float64, offsetType OffsetType) float64 {
+	if offsetType == OffsetPercent {
+		return price * (1 + offset/100)
+	}
+	return price + offset
+}
+
 type BuyStruct struct {
-	Offset int
-	Price  float64
-	ID     string
+	Offset      float64
+	OffsetType  OffsetType
+	Price       float64
+	ID          string
+	ExecutedQty float64
+	Fee         float64
+	FeeAsset    string
 }
 
 type SellStruct struct {
-	Offset int
-	Price  float64
-	ID     string
+	Offset      float64
+	OffsetType  OffsetType
+	Price       float64
+	ID          string
+	ExecutedQty float64
+	Fee         float64
+	FeeAsset    string
+	TrailHigh   float64 // highest price seen above the sell price, 0 until reached
 }
 
 type MetaData struct {
@@ -33,16 +74,60 @@ type MetaData struct {
 	USDDedicated   float64
 	Percent        float64
 	BTCPrice       float64
+	Volatility     float64 // measured when the offsets follow the volatility, in the quote asset
 }
 
 type Cycle struct {
-	Id       int
-	Exchange string
-	Status   Status
-	Quantity float64
-	Buy      BuyStruct
-	Sell     SellStruct
-	MetaData MetaData
+	Id        int
+	Exchange  string
+	Symbol    string
+	Status    Status
+	Quantity  float64
+	Buy       BuyStruct
+	Sell      SellStruct
+	MetaData  MetaData
+	CreatedAt int64 // unix milliseconds, 0 for cycles created before it was recorded
+	GridLevel int   // level of the grid the cycle buys on, from 1 below the anchor, 0 outside the grid
+}
+
+// Columns of the cycles table, in the order scanCycle reads them
+const cycleColumns = "id, exchange, status, quantity, buyPrice, buyId, sellPrice, sellId, freeBalance, dedicatedBalance, buyOffset, sellOffset, percent, btcPrice, symbol, buyExecutedQty, sellExecutedQty, buyFee, buyFeeAsset, sellFee, sellFeeAsset, createdAt, trailHigh, buyOffsetType, sellOffsetType, volatility, gridLevel"
+
+func scanCycle(rows *sql.Rows) (*Cycle, error) {
+	var cycle Cycle
+	err := rows.Scan(
+		&cycle.Id,
+		&cycle.Exchange,
+		&cycle.Status,
+		&cycle.Quantity,
+		&cycle.Buy.Price,
+		&cycle.Buy.ID,
+		&cycle.Sell.Price,
+		&cycle.Sell.ID,
+		&cycle.MetaData.FreeBalanceUSD,
+		&cycle.MetaData.USDDedicated,
+		&cycle.Buy.Offset,
+		&cycle.Sell.Offset,
+		&cycle.MetaData.Percent,
+		&cycle.MetaData.BTCPrice,
+		&cycle.Symbol,
+		&cycle.Buy.ExecutedQty,
+		&cycle.Sell.ExecutedQty,
+		&cycle.Buy.Fee,
+		&cycle.Buy.FeeAsset,
+		&cycle.Sell.Fee,
+		&cycle.Sell.FeeAsset,
+		&cycle.CreatedAt,
+		&cycle.Sell.TrailHigh,
+		&cycle.Buy.OffsetType,
+		&cycle.Sell.OffsetType,
+		&cycle.MetaData.Volatility,
+		&cycle.GridLevel,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &cycle, nil
 }
 
 func CycleNew(cycle *Cycle) (int64, error) {
@@ -55,7 +140,7 @@ func CycleNew(cycle *Cycle) (int64, error) {
 	// Retry INSERT on transient SQLITE_BUSY/database is locked errors
 	var res sql.Result
 	for attempt := 0; attempt < 5; attempt++ {
-		res, err = db.Exec("INSERT INTO cycles (exchange, status, quantity, buyPrice, buyId, sellPrice, sellId, freeBalance, dedicatedBalance, buyOffset, sellOffset, percent, btcPrice) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id", cycle.Exchange, cycle.Status, cycle.Quantity, cycle.Buy.Price, cycle.Buy.ID, cycle.Sell.Price, cycle.Sell.ID, cycle.MetaData.FreeBalanceUSD, cycle.MetaData.USDDedicated, cycle.Buy.Offset, cycle.Sell.Offset, cycle.MetaData.Percent, cycle.MetaData.BTCPrice)
+		res, err = db.Exec("INSERT INTO cycles (exchange, status, quantity, buyPrice, buyId, sellPrice, sellId, freeBalance, dedicatedBalance, buyOffset, sellOffset, percent, btcPrice, symbol, buyExecutedQty, sellExecutedQty, buyFee, buyFeeAsset, sellFee, sellFeeAsset, createdAt, trailHigh, buyOffsetType, sellOffsetType, volatility, gridLevel) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id", cycle.Exchange, cycle.Status, cycle.Quantity, cycle.Buy.Price, cycle.Buy.ID, cycle.Sell.Price, cycle.Sell.ID, cycle.MetaData.FreeBalanceUSD, cycle.MetaData.USDDedicated, cycle.Buy.Offset, cycle.Sell.Offset, cycle.MetaData.Percent, cycle.MetaData.BTCPrice, cycle.Symbol, cycle.Buy.ExecutedQty, cycle.Sell.ExecutedQty, cycle.Buy.Fee, cycle.Buy.FeeAsset, cycle.Sell.Fee, cycle.Sell.FeeAsset, cycle.CreatedAt, cycle.Sell.TrailHigh, cycle.Buy.OffsetType, cycle.Sell.OffsetType, cycle.MetaData.Volatility, cycle.GridLevel)
 		if err == nil {
 			break
 		}
@@ -86,34 +171,18 @@ func CycleList() ([]Cycle, error) {
 
 	var cycles []Cycle
 
-	rows, err := db.Query("SELECT * FROM cycles ORDER BY id DESC ")
+	rows, err := db.Query("SELECT " + cycleColumns + " FROM cycles ORDER BY id DESC")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var cycle Cycle
-		err := rows.Scan(
-			&cycle.Id,
-			&cycle.Exchange,
-			&cycle.Status,
-			&cycle.Quantity,
-			&cycle.Buy.Price,
-			&cycle.Buy.ID,
-			&cycle.Sell.Price,
-			&cycle.Sell.ID,
-			&cycle.MetaData.FreeBalanceUSD,
-			&cycle.MetaData.USDDedicated,
-			&cycle.Buy.Offset,
-			&cycle.Sell.Offset,
-			&cycle.MetaData.Percent,
-			&cycle.MetaData.BTCPrice,
-		)
+		cycle, err := scanCycle(rows)
 		if err != nil {
 			return nil, err
 		}
-		cycles = append(cycles, cycle)
+		cycles = append(cycles, *cycle)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -130,7 +199,7 @@ func CycleGetById(id int) (*Cycle, error) {
 	}
 	defer func() { _ = db.Close() }()
 
-	rows, err := db.Query("SELECT * FROM cycles WHERE id = ? LIMIT 1", id)
+	rows, err := db.Query("SELECT "+cycleColumns+" FROM cycles WHERE id = ? LIMIT 1", id)
 	if err != nil {
 		return nil, err
 	}
@@ -148,8 +217,7 @@ func CycleGetById(id int) (*Cycle, error) {
 		return nil, sql.ErrNoRows
 	}
 
-	var cycle Cycle
-	err = rows.Scan(&cycle.Id, &cycle.Exchange, &cycle.Status, &cycle.Quantity, &cycle.Buy.Price, &cycle.Buy.ID, &cycle.Sell.Price, &cycle.Sell.ID, &cycle.MetaData.FreeBalanceUSD, &cycle.MetaData.USDDedicated, &cycle.Buy.Offset, &cycle.Sell.Offset, &cycle.MetaData.Percent, &cycle.MetaData.BTCPrice)
+	cycle, err := scanCycle(rows)
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +226,7 @@ func CycleGetById(id int) (*Cycle, error) {
 		return nil, err
 	}
 
-	return &cycle, nil
+	return cycle, nil
 }
 
 func CycleDeleteById(id int) error {
@@ -191,7 +259,7 @@ func CycleListPerPage(page int, itemsPerPage int) ([]Cycle, error) {
 	defer func() { _ = db.Close() }()
 
 	skip := (page - 1) * itemsPerPage
-	rows, err := db.Query("SELECT * FROM cycles ORDER BY id DESC LIMIT ? OFFSET ?", itemsPerPage, skip)
+	rows, err := db.Query("SELECT "+cycleColumns+" FROM cycles ORDER BY id DESC LIMIT ? OFFSET ?", itemsPerPage, skip)
 	if err != nil {
 		return nil, err
 	}
@@ -204,12 +272,11 @@ func CycleListPerPage(page int, itemsPerPage int) ([]Cycle, error) {
 
 	var cycles []Cycle
 	for rows.Next() {
-		var cycle Cycle
-		err = rows.Scan(&cycle.Id, &cycle.Exchange, &cycle.Status, &cycle.Quantity, &cycle.Buy.Price, &cycle.Buy.ID, &cycle.Sell.Price, &cycle.Sell.ID, &cycle.MetaData.FreeBalanceUSD, &cycle.MetaData.USDDedicated, &cycle.Buy.Offset, &cycle.Sell.Offset, &cycle.MetaData.Percent, &cycle.MetaData.BTCPrice)
+		cycle, err := scanCycle(rows)
 		if err != nil {
 			return nil, err
 		}
-		cycles = append(cycles, cycle)
+		cycles = append(cycles, *cycle)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -246,6 +313,17 @@ func CycleUpdate(id int, field string, value interface{}) (sql.Result, error) {
 }
 
 // helpers
+
+// IsOpen reports whether the cycle still holds funds, in a buy order or in
+// what it bought and did not sell yet
+func (c *Cycle) IsOpen() bool {
+	switch c.Status {
+	case Pending, Buy, Sell, Trailing, Stopping:
+		return true
+	}
+	return false
+}
+
 func (c *Cycle) CalcPercent() float64 {
 	totalBuy := c.Buy.Price * c.Quantity
 	totalSell := c.Sell.Price * c.Quantity
@@ -262,25 +340,63 @@ func (c *Cycle) CalcProfit() float64 {
 	return profit
 }
 
+// Converts a fee to the quote asset. A fee in the base asset is worth its
+// quantity at price, a fee in a third asset (BNB) can not be converted and
+// counts for nothing.
+func (c *Cycle) feeInQuote(fee float64, asset string, price float64) float64 {
+	base, quote, _ := strings.Cut(c.Symbol, "/")
+	switch asset {
+	case "", quote:
+		return fee
+	case base:
+		return fee * price
+	}
+	return 0
+}
+
+// Fees returns the buy and sell fees of the cycle in the quote asset
+func (c *Cycle) Fees() float64 {
+	return c.feeInQuote(c.Buy.Fee, c.Buy.FeeAsset, c.Buy.Price) + c.feeInQuote(c.Sell.Fee, c.Sell.FeeAsset, c.Sell.Price)
+}
+
+// CalcNetProfit is CalcProfit minus the fees
+func (c *Cycle) CalcNetProfit() float64 {
+	return c.CalcProfit() - c.Fees()
+}
+
+func (c *Cycle) CalcNetPercent() float64 {
+	totalBuy := c.Buy.Price * c.Quantity
+	return c.CalcNetProfit() / totalBuy * 100
+}
+
 // String returns a detailed string representation of a Cycle, useful for logs.
 func (c Cycle) String() string {
 	return fmt.Sprintf(
-		"Cycle{id:%d, ex:%s, status:%s, qty:%.8f, buy:{off:%d price:%.8f id:%s}, sell:{off:%d price:%.8f id:%s}, meta:{freeUSD:%.2f dedicatedUSD:%.2f percent:%d btc:%.2f}, profit:%.8f, pct:%.4f%%}",
+		"Cycle{id:%d, ex:%s, symbol:%s, status:%s, qty:%.8f, buy:{off:%s price:%.8f id:%s executed:%.8f fee:%.8f %s}, sell:{off:%s price:%.8f id:%s executed:%.8f fee:%.8f %s}, meta:{freeUSD:%.2f dedicatedUSD:%.2f percent:%.2f price:%.2f volatility:%.2f}, profit:%.8f, net:%.8f, pct:%.4f%%}",
 		c.Id,
 		c.Exchange,
+		c.Symbol,
 		c.Status,
 		c.Quantity,
-		c.Buy.Offset,
+		FormatOffset(c.Buy.Offset, c.Buy.OffsetType),
 		c.Buy.Price,
 		c.Buy.ID,
-		c.Sell.Offset,
+		c.Buy.ExecutedQty,
+		c.Buy.Fee,
+		c.Buy.FeeAsset,
+		FormatOffset(c.Sell.Offset, c.Sell.OffsetType),
 		c.Sell.Price,
 		c.Sell.ID,
+		c.Sell.ExecutedQty,
+		c.Sell.Fee,
+		c.Sell.FeeAsset,
 		c.MetaData.FreeBalanceUSD,
 		c.MetaData.USDDedicated,
 		c.MetaData.Percent,
 		c.MetaData.BTCPrice,
+		c.MetaData.Volatility,
 		c.CalcProfit(),
+		c.CalcNetProfit(),
 		c.CalcPercent(),
 	)
 }
@@ -1,17 +1,54 @@
 package database_test
 
 import (
+	"context"
 	"github.com/joho/godotenv"
 	"log"
 	"main/commands"
 	"main/database"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
+// TestMain points the database to a temporary home so tests never touch the
+// real one. The tests after TestCycleNew read the cycles it creates.
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "database_test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	_ = os.Setenv("HOME", home)
+
+	err = database.InitDatabase()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(home)
+	os.Exit(code)
+}
+
 func TestCycleNew(t *testing.T) {
 	godotenv.Load("../bot.conf")
 
-	cycle, err := commands.PrepareNewCycle()
+	// A paper cycle priced by a replay
+	dir := t.TempDir()
+	t.Setenv("EXCHANGE", "PAPER")
+	t.Setenv("SYMBOL", "BTC/USDC")
+	t.Setenv("PERCENT", "10")
+	t.Setenv("BUY_OFFSET", "-1000")
+	t.Setenv("SELL_OFFSET", "1000")
+	t.Setenv("PAPER_FEED", "CSV")
+	t.Setenv("PAPER_CSV", filepath.Join(dir, "prices.csv"))
+	err := os.WriteFile(filepath.Join(dir, "prices.csv"), []byte("100000\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cycle, err := commands.PrepareNewCycle(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -75,3 +112,26 @@ func TestCycleUpdate(t *testing.T) {
 	}
 	t.Log(result)
 }
+
+func TestCycleNetProfit(t *testing.T) {
+	cycle := database.Cycle{
+		Symbol:   "BTC/USDC",
+		Quantity: 0.001,
+		Buy:      database.BuyStruct{Price: 90000, Fee: 0.000001, FeeAsset: "BTC"},
+		Sell:     database.SellStruct{Price: 92000, Fee: 0.092, FeeAsset: "USDC"},
+	}
+
+	// 2 gross, 0.09 + 0.092 of fees
+	if profit := cycle.CalcProfit(); math.Abs(profit-2) > 1e-9 {
+		t.Errorf("expected gross profit 2, got %v", profit)
+	}
+	if net := cycle.CalcNetProfit(); math.Abs(net-1.818) > 1e-9 {
+		t.Errorf("expected net profit 1.818, got %v", net)
+	}
+
+	// A fee in another asset can not be converted
+	cycle.Sell.FeeAsset = "BNB"
+	if net := cycle.CalcNetProfit(); math.Abs(net-1.91) > 1e-9 {
+		t.Errorf("expected net profit 1.91, got %v", net)
+	}
+}
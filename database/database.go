@@ -123,6 +123,60 @@ func InitDatabase() (err error) {
 	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN btcPrice REAL"); err != nil {
 		return err
 	}
+	// Cycles created before symbols existed were all BTC/USDC
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN symbol TEXT DEFAULT 'BTC/USDC'"); err != nil {
+		return err
+	}
+
+	// Quantities actually executed by the buy and sell orders
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN buyExecutedQty REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN sellExecutedQty REAL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Commissions of the buy and sell orders, in their fee asset
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN buyFee REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN buyFeeAsset TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN sellFee REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN sellFeeAsset TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+
+	// Creation time in unix milliseconds, 0 for the cycles created before
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN createdAt INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Highest price of a trailing take-profit
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN trailHigh REAL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Offsets were amounts before they could be percentages
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN buyOffsetType TEXT DEFAULT 'absolute'"); err != nil {
+		return err
+	}
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN sellOffsetType TEXT DEFAULT 'absolute'"); err != nil {
+		return err
+	}
+
+	// Volatility the offsets were computed from, 0 for fixed offsets
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN volatility REAL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Level of the grid mode, 0 for the cycles outside the grid
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE cycles ADD COLUMN gridLevel INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
 
 	// Create table cfg_items
 	_, err = db.Exec("CREATE TABLE IF NOT EXISTS cfg_items (key TEXT PRIMARY KEY, value TEXT)")
@@ -130,6 +184,18 @@ func InitDatabase() (err error) {
 		return err
 	}
 
+	// Create table paper_orders, the order book of the PAPER exchange
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS paper_orders (id INTEGER PRIMARY KEY, side TEXT, price REAL, quantity REAL, status TEXT, createdAt INTEGER, updatedAt INTEGER)")
+	if err != nil {
+		return err
+	}
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE paper_orders ADD COLUMN symbol TEXT DEFAULT 'BTC/USDC'"); err != nil {
+		return err
+	}
+	if err = execAndIgnoreDuplicateColumn("ALTER TABLE paper_orders ADD COLUMN clientId TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type PaperOrderStatus string
+
+const (
+	PaperNew      PaperOrderStatus = "NEW"
+	PaperFilled   PaperOrderStatus = "FILLED"
+	PaperCanceled PaperOrderStatus = "CANCELED"
+)
+
+// PaperOrder is a limit order of the PAPER exchange
+type PaperOrder struct {
+	Id        int
+	ClientId  string
+	Symbol    string
+	Side      string
+	Price     float64
+	Quantity  float64
+	Status    PaperOrderStatus
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+func PaperOrderNew(order *PaperOrder) (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, fmt.Errorf("error getting database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var res sql.Result
+	for attempt := 0; attempt < 5; attempt++ {
+		res, err = db.Exec("INSERT INTO paper_orders (clientId, symbol, side, price, quantity, status, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", order.ClientId, order.Symbol, order.Side, order.Price, order.Quantity, order.Status, order.CreatedAt, order.UpdatedAt)
+		if err == nil {
+			break
+		}
+		if strings.Contains(err.Error(), "SQLITE_BUSY") || strings.Contains(err.Error(), "database is locked") {
+			time.Sleep(time.Duration(100*(attempt+1)) * time.Millisecond)
+			continue
+		}
+		return 0, fmt.Errorf("error inserting paper order: %v", err)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error inserting paper order after retries: %v", err)
+	}
+
+	return res.LastInsertId()
+}
+
+func PaperOrderGetById(id int) (*PaperOrder, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	var order PaperOrder
+	err = db.QueryRow("SELECT id, clientId, symbol, side, price, quantity, status, createdAt, updatedAt FROM paper_orders WHERE id = ?", id).
+		Scan(&order.Id, &order.ClientId, &order.Symbol, &order.Side, &order.Price, &order.Quantity, &order.Status, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// PaperOrderGetByClientId returns the last order sent with clientId
+func PaperOrderGetByClientId(clientId string) (*PaperOrder, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	var order PaperOrder
+	err = db.QueryRow("SELECT id, clientId, symbol, side, price, quantity, status, createdAt, updatedAt FROM paper_orders WHERE clientId = ? ORDER BY id DESC LIMIT 1", clientId).
+		Scan(&order.Id, &order.ClientId, &order.Symbol, &order.Side, &order.Price, &order.Quantity, &order.Status, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func PaperOrderListByStatus(status PaperOrderStatus) ([]PaperOrder, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	rows, err := db.Query("SELECT id, clientId, symbol, side, price, quantity, status, createdAt, updatedAt FROM paper_orders WHERE status = ? ORDER BY id", status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []PaperOrder
+	for rows.Next() {
+		var order PaperOrder
+		err := rows.Scan(&order.Id, &order.ClientId, &order.Symbol, &order.Side, &order.Price, &order.Quantity, &order.Status, &order.CreatedAt, &order.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func PaperOrderUpdateStatus(id int, status PaperOrderStatus) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	for attempt := 0; attempt < 5; attempt++ {
+		_, err = db.Exec("UPDATE paper_orders SET status = ?, updatedAt = ? WHERE id = ?", status, time.Now().UnixMilli(), id)
+		if err == nil {
+			return nil
+		}
+		if strings.Contains(err.Error(), "SQLITE_BUSY") || strings.Contains(err.Error(), "database is locked") {
+			time.Sleep(time.Duration(100*(attempt+1)) * time.Millisecond)
+			continue
+		}
+		return err
+	}
+	return err
+}
@@ -0,0 +1,304 @@
+package binance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/buger/jsonparser"
+	"github.com/fatih/color"
+	"io"
+	"main/exchanges"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Client struct {
+	APIKey    string
+	APISecret string
+	BaseURL   string
+}
+
+func NewClient() *Client {
+	return &Client{
+		APIKey:    os.Getenv("BINANCE_API_KEY"),
+		APISecret: os.Getenv("BINANCE_SECRET_KEY"),
+		BaseURL:   "https://api.binance.com",
+	}
+}
+
+func (c *Client) SetBaseURL(url string) {
+	c.BaseURL = url
+}
+
+// Generates HMAC SHA256 signature for a signed request
+func (c *Client) signRequest(queryString string) string {
+	h := hmac.New(sha256.New, []byte(c.APISecret))
+	h.Write([]byte(queryString))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Sends an HTTP request and returns the response body, within
+// exchanges.CallTimeout
+func (c *Client) sendRequest(ctx context.Context, method, endpoint, queryString string) ([]byte, error) {
+	fullURL := fmt.Sprintf("%s%s?%s", c.BaseURL, endpoint, queryString)
+
+	ctx, cancel := context.WithTimeout(ctx, exchanges.CallTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-MBX-APIKEY", c.APIKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, exchanges.NetworkError("Binance", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(resp.StatusCode, body)
+	}
+
+	return body, nil
+}
+
+// Signs queryString with a fresh timestamp and sends it
+func (c *Client) sendSignedRequest(ctx context.Context, method, endpoint, queryString string) ([]byte, error) {
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	if queryString != "" {
+		queryString += "&"
+	}
+	queryString += "timestamp=" + timestamp
+
+	signature := c.signRequest(queryString)
+	signedQuery := fmt.Sprintf("%s&signature=%s", queryString, signature)
+
+	return c.sendRequest(ctx, method, endpoint, signedQuery)
+}
+
+func (c *Client) CheckConnection(ctx context.Context) error {
+	_, err := c.sendRequest(ctx, "GET", "/api/v3/ping", "")
+	if err != nil {
+		return fmt.Errorf("failed to connect to Binance: %w", err)
+	}
+
+	color.Green("Connected to Binance API successfully")
+	fmt.Println("")
+	return nil
+}
+
+func (c *Client) GetBalance(ctx context.Context, asset string) (float64, error) {
+	color.Blue("Checking %s balance...", asset)
+
+	body, err := c.sendSignedRequest(ctx, "GET", "/api/v3/account", "omitZeroBalances=true")
+	if err != nil {
+		return 0, fmt.Errorf("error fetching balance: %w", err)
+	}
+
+	balances, _, _, err := jsonparser.Get(body, "balances")
+	if err != nil {
+		return 0, fmt.Errorf("error getting balances: %v", err)
+	}
+
+	var freeFloat float64
+	_, err = jsonparser.ArrayEach(balances, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		balanceAsset, _ := jsonparser.GetString(value, "asset")
+		if balanceAsset == asset {
+			freeStr, _ := jsonparser.GetString(value, "free")
+			free, _ := strconv.ParseFloat(freeStr, 64)
+			freeFloat = free
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error getting balances: %v", err)
+	}
+
+	return freeFloat, nil
+}
+
+func (c *Client) GetLastPrice(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	body, err := c.sendRequest(ctx, "GET", "/api/v3/ticker/price", "symbol="+symbol.Join(""))
+	if err != nil {
+		return 0, fmt.Errorf("error fetching %s price: %w", symbol, err)
+	}
+
+	priceStr, err := jsonparser.GetString(body, "price")
+	if err != nil {
+		return 0, fmt.Errorf("error extracting price: %v", err)
+	}
+
+	price, err := strconv.ParseFloat(priceStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error converting price: %v", err)
+	}
+
+	return price, nil
+}
+
+// CreateOrder places a limit order. clientId, when set, is sent as
+// newClientOrderId so the order can be found without its id.
+func (c *Client) CreateOrder(ctx context.Context, symbol exchanges.Symbol, side string, price, quantity, clientId string) (*exchanges.Order, error) {
+	// Binance requires a time in force on LIMIT orders
+	queryString := fmt.Sprintf(
+		"symbol=%s&side=%s&type=LIMIT&timeInForce=GTC&quantity=%s&price=%s",
+		symbol.Join(""), side, quantity, price,
+	)
+	if clientId != "" {
+		queryString += "&newClientOrderId=" + url.QueryEscape(clientId)
+	}
+
+	body, err := c.sendSignedRequest(ctx, "POST", "/api/v3/order", queryString)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	return exchanges.ParseBinanceOrder(symbol, body, orderStatus)
+}
+
+func (c *Client) GetOrderById(ctx context.Context, symbol exchanges.Symbol, id string) (*exchanges.Order, error) {
+	body, err := c.sendSignedRequest(ctx, "GET", "/api/v3/order", "symbol="+symbol.Join("")+"&orderId="+id)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	return exchanges.ParseBinanceOrder(symbol, body, orderStatus)
+}
+
+func (c *Client) GetOrderByClientId(ctx context.Context, symbol exchanges.Symbol, clientId string) (*exchanges.Order, error) {
+	body, err := c.sendSignedRequest(ctx, "GET", "/api/v3/order", "symbol="+symbol.Join("")+"&origClientOrderId="+url.QueryEscape(clientId))
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	return exchanges.ParseBinanceOrder(symbol, body, orderStatus)
+}
+
+func (c *Client) CancelOrder(ctx context.Context, symbol exchanges.Symbol, orderID string) (*exchanges.Order, error) {
+	body, err := c.sendSignedRequest(ctx, "DELETE", "/api/v3/order", "symbol="+symbol.Join("")+"&orderId="+orderID)
+	if err != nil {
+		return nil, fmt.Errorf("error canceling order %s: %w", orderID, err)
+	}
+
+	color.Green("Order %s canceled successfully", orderID)
+	return exchanges.ParseBinanceOrder(symbol, body, orderStatus)
+}
+
+func (c *Client) GetOpenOrders(ctx context.Context, symbol exchanges.Symbol) ([]exchanges.Order, error) {
+	body, err := c.sendSignedRequest(ctx, "GET", "/api/v3/openOrders", "symbol="+symbol.Join(""))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching open orders: %w", err)
+	}
+
+	return exchanges.ParseBinanceOrders(symbol, body, orderStatus)
+}
+
+// GetOrderTrades returns the fills of an order with their commission
+func (c *Client) GetOrderTrades(ctx context.Context, symbol exchanges.Symbol, orderID string) ([]exchanges.Trade, error) {
+	body, err := c.sendSignedRequest(ctx, "GET", "/api/v3/myTrades", "symbol="+symbol.Join("")+"&orderId="+orderID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching trades of order %s: %w", orderID, err)
+	}
+
+	return exchanges.ParseBinanceTrades(orderID, body)
+}
+
+// GetSymbolFilters reads PRICE_FILTER, LOT_SIZE and NOTIONAL (MIN_NOTIONAL
+// on older symbols) of symbol from exchangeInfo
+func (c *Client) GetSymbolFilters(ctx context.Context, symbol exchanges.Symbol) (exchanges.SymbolFilters, error) {
+	var filters exchanges.SymbolFilters
+
+	body, err := c.sendRequest(ctx, "GET", "/api/v3/exchangeInfo", "symbol="+symbol.Join(""))
+	if err != nil {
+		return filters, fmt.Errorf("error fetching exchange info: %w", err)
+	}
+
+	list, _, _, err := jsonparser.Get(body, "symbols", "[0]", "filters")
+	if err != nil {
+		return filters, fmt.Errorf("symbol %s not found in exchange info", symbol)
+	}
+
+	parse := func(value []byte, key string) float64 {
+		str, _ := jsonparser.GetString(value, key)
+		f, _ := strconv.ParseFloat(str, 64)
+		return f
+	}
+
+	_, err = jsonparser.ArrayEach(list, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		filterType, _ := jsonparser.GetString(value, "filterType")
+		switch filterType {
+		case "PRICE_FILTER":
+			filters.TickSize = parse(value, "tickSize")
+		case "LOT_SIZE":
+			filters.StepSize = parse(value, "stepSize")
+			filters.MinQty = parse(value, "minQty")
+		case "NOTIONAL", "MIN_NOTIONAL":
+			filters.MinNotional = parse(value, "minNotional")
+		}
+	})
+	if err != nil {
+		return filters, fmt.Errorf("error parsing filters: %v", err)
+	}
+
+	return filters, nil
+}
+
+// Kline intervals of Binance
+var klineIntervals = map[time.Duration]string{
+	time.Minute:        "1m",
+	3 * time.Minute:    "3m",
+	5 * time.Minute:    "5m",
+	15 * time.Minute:   "15m",
+	30 * time.Minute:   "30m",
+	time.Hour:          "1h",
+	2 * time.Hour:      "2h",
+	4 * time.Hour:      "4h",
+	6 * time.Hour:      "6h",
+	8 * time.Hour:      "8h",
+	12 * time.Hour:     "12h",
+	24 * time.Hour:     "1d",
+	3 * 24 * time.Hour: "3d",
+	7 * 24 * time.Hour: "1w",
+}
+
+// GetKlines returns the last limit klines of symbol, oldest first. The last
+// one is still open.
+func (c *Client) GetKlines(ctx context.Context, symbol exchanges.Symbol, interval time.Duration, limit int) ([]exchanges.Kline, error) {
+	name, err := exchanges.KlineInterval("Binance", interval, klineIntervals)
+	if err != nil {
+		return nil, err
+	}
+
+	queryString := fmt.Sprintf("symbol=%s&interval=%s&limit=%d", symbol.Join(""), name, limit)
+	body, err := c.sendRequest(ctx, "GET", "/api/v3/klines", queryString)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s klines: %w", symbol, err)
+	}
+
+	return exchanges.ParseBinanceKlines(body)
+}
+
+func orderStatus(status string) exchanges.OrderStatus {
+	switch status {
+	case "PENDING_CANCEL":
+		return exchanges.OrderCanceled
+	case "EXPIRED_IN_MATCH":
+		return exchanges.OrderExpired
+	}
+	return exchanges.OrderStatus(status)
+}
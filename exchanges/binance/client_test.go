@@ -0,0 +1,278 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"io"
+	"main/exchanges"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var client *Client
+
+var btcusdc = exchanges.DefaultSymbol
+
+// fakeBinance is a minimal stand-in of the Binance spot /api/v3 REST API.
+// Signed endpoints reject any request whose signature does not match.
+func fakeBinance() *httptest.Server {
+	orders := map[string]string{
+		"1001": `{"symbol":"BTCUSDC","orderId":1001,"price":"90000.00","origQty":"0.00010000","executedQty":"0.00010000","status":"FILLED","type":"LIMIT","side":"BUY"}`,
+		"1002": `{"symbol":"BTCUSDC","orderId":1002,"price":"95000.00","origQty":"0.00010000","executedQty":"0.00000000","status":"NEW","type":"LIMIT","side":"SELL"}`,
+	}
+
+	signed := map[string]bool{
+		"/api/v3/account":    true,
+		"/api/v3/order":      true,
+		"/api/v3/openOrders": true,
+		"/api/v3/myTrades":   true,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		if signed[r.URL.Path] {
+			raw := r.URL.RawQuery
+			i := strings.LastIndex(raw, "&signature=")
+			if i < 0 || r.Header.Get("X-MBX-APIKEY") != client.APIKey ||
+				client.signRequest(raw[:i]) != query.Get("signature") || query.Get("timestamp") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = io.WriteString(w, `{"code":-1022,"msg":"Signature for this request is not valid."}`)
+				return
+			}
+		}
+
+		switch {
+		case r.URL.Path == "/api/v3/ping":
+			_, _ = io.WriteString(w, `{}`)
+		case r.URL.Path == "/api/v3/account":
+			_, _ = io.WriteString(w, `{"balances":[{"asset":"BTC","free":"0.001","locked":"0"},{"asset":"USDC","free":"250.75","locked":"10"}]}`)
+		case r.URL.Path == "/api/v3/exchangeInfo":
+			_, _ = io.WriteString(w, `{"symbols":[{"symbol":"`+query.Get("symbol")+`","filters":[`+
+				`{"filterType":"PRICE_FILTER","minPrice":"0.01","maxPrice":"1000000.00","tickSize":"0.01"},`+
+				`{"filterType":"LOT_SIZE","minQty":"0.00001","maxQty":"9000.00","stepSize":"0.00001"},`+
+				`{"filterType":"NOTIONAL","minNotional":"5.00","applyMinToMarket":true}]}]}`)
+		case r.URL.Path == "/api/v3/ticker/price":
+			_, _ = io.WriteString(w, `{"symbol":"`+query.Get("symbol")+`","price":"91234.56000000"}`)
+		case r.URL.Path == "/api/v3/order" && r.Method == "POST":
+			if query.Get("type") != "LIMIT" || query.Get("timeInForce") != "GTC" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `{"code":-1102,"msg":"Mandatory parameter 'timeInForce' was not sent."}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{"symbol":"BTCUSDC","orderId":1003,"status":"NEW","side":"`+query.Get("side")+`"}`)
+		case r.URL.Path == "/api/v3/order" && r.Method == "GET":
+			id := query.Get("orderId")
+			if query.Get("origClientOrderId") == "bs-1a2b-7-buy" {
+				id = "1001"
+			}
+			order, ok := orders[id]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `{"code":-2013,"msg":"Order does not exist."}`)
+				return
+			}
+			_, _ = io.WriteString(w, order)
+		case r.URL.Path == "/api/v3/order" && r.Method == "DELETE":
+			_, _ = io.WriteString(w, `{"symbol":"BTCUSDC","orderId":`+query.Get("orderId")+`,"status":"CANCELED"}`)
+		case r.URL.Path == "/api/v3/myTrades":
+			if query.Get("orderId") != "1001" {
+				_, _ = io.WriteString(w, `[]`)
+				return
+			}
+			_, _ = io.WriteString(w, `[`+
+				`{"symbol":"BTCUSDC","id":501,"orderId":1001,"price":"90000.00","qty":"0.00006000","quoteQty":"5.40","commission":"0.00000006","commissionAsset":"BTC","time":1760432460123},`+
+				`{"symbol":"BTCUSDC","id":502,"orderId":1001,"price":"90000.00","qty":"0.00004000","quoteQty":"3.60","commission":"0.00000004","commissionAsset":"BTC","time":1760432461123}]`)
+		case r.URL.Path == "/api/v3/klines":
+			if query.Get("interval") != "1h" || query.Get("limit") != "2" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `{"code":-1120,"msg":"Invalid interval."}`)
+				return
+			}
+			_, _ = io.WriteString(w, `[`+
+				`[1760428800000,"90000.00","91000.00","89500.00","90500.00","12.5",1760432399999,"1131250.00",1000,"6.2","561100.00","0"],`+
+				`[1760432400000,"90500.00","91200.00","90100.00","91100.00","8.25",1760435999999,"751575.00",800,"4.1","373510.00","0"]]`)
+		case r.URL.Path == "/api/v3/openOrders":
+			_, _ = io.WriteString(w, `[`+orders["1002"]+`]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestMain(m *testing.M) {
+	client = &Client{
+		APIKey:    "key",
+		APISecret: "secret",
+	}
+
+	server := fakeBinance()
+	client.SetBaseURL(server.URL)
+
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
+func TestCheckConnection(t *testing.T) {
+	err := client.CheckConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	balance, err := client.GetBalance(context.Background(), "USDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 250.75 {
+		t.Errorf("expected balance 250.75, got %v", balance)
+	}
+}
+
+func TestGetLastPrice(t *testing.T) {
+	price, err := client.GetLastPrice(context.Background(), btcusdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != 91234.56 {
+		t.Errorf("expected price 91234.56, got %v", price)
+	}
+}
+
+func TestCreateOrder(t *testing.T) {
+	order, err := client.CreateOrder(context.Background(), btcusdc, "BUY", "90000", "0.0001", "bs-1a2b-7-buy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Id != "1003" || order.Status != exchanges.OrderNew || order.Side != "BUY" {
+		t.Errorf("expected new BUY order 1003, got %+v", order)
+	}
+}
+
+func TestGetOrderById(t *testing.T) {
+	order, err := client.GetOrderById(context.Background(), btcusdc, "1002")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Side != "SELL" || order.Price != 95000 || order.OrigQty != 0.0001 {
+		t.Errorf("expected SELL 0.0001 at 95000, got %+v", order)
+	}
+
+	_, err = client.GetOrderById(context.Background(), btcusdc, "404")
+	if !errors.Is(err, exchanges.ErrOrderNotFound) {
+		t.Errorf("expected an order not found error, got %v", err)
+	}
+}
+
+func TestGetOrderByClientId(t *testing.T) {
+	order, err := client.GetOrderByClientId(context.Background(), btcusdc, "bs-1a2b-7-buy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Id != "1001" {
+		t.Errorf("expected order 1001, got %+v", order)
+	}
+
+	_, err = client.GetOrderByClientId(context.Background(), btcusdc, "bs-1a2b-8-buy")
+	if !errors.Is(err, exchanges.ErrOrderNotFound) {
+		t.Errorf("expected an order not found error, got %v", err)
+	}
+}
+
+func TestIsFilled(t *testing.T) {
+	for id, expected := range map[string]bool{"1001": true, "1002": false} {
+		order, err := client.GetOrderById(context.Background(), btcusdc, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if order.IsFilled() != expected {
+			t.Errorf("%s: expected filled=%v, got %v", id, expected, order.IsFilled())
+		}
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	order, err := client.CancelOrder(context.Background(), btcusdc, "1002")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Status != exchanges.OrderCanceled {
+		t.Errorf("expected status CANCELED, got %s", order.Status)
+	}
+}
+
+func TestGetOpenOrders(t *testing.T) {
+	orders, err := client.GetOpenOrders(context.Background(), btcusdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders) != 1 || orders[0].Id != "1002" {
+		t.Errorf("expected order 1002 in open orders, got %+v", orders)
+	}
+}
+
+func TestGetOrderTrades(t *testing.T) {
+	trades, err := client.GetOrderTrades(context.Background(), btcusdc, "1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 || trades[0].Id != "501" || trades[1].Qty != 0.00004 {
+		t.Fatalf("unexpected trades %+v", trades)
+	}
+
+	fee, asset := exchanges.TotalFee(trades)
+	if asset != "BTC" || fee < 0.0000000999 || fee > 0.0000001001 {
+		t.Errorf("expected 0.0000001 BTC of fees, got %v %s", fee, asset)
+	}
+}
+
+func TestGetSymbolFilters(t *testing.T) {
+	filters, err := client.GetSymbolFilters(context.Background(), btcusdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := exchanges.SymbolFilters{TickSize: 0.01, StepSize: 0.00001, MinQty: 0.00001, MinNotional: 5}
+	if filters != expected {
+		t.Errorf("expected %+v, got %+v", expected, filters)
+	}
+}
+
+func TestGetKlines(t *testing.T) {
+	klines, err := client.GetKlines(context.Background(), btcusdc, time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(klines) != 2 {
+		t.Fatalf("expected 2 klines, got %d", len(klines))
+	}
+	expected := exchanges.Kline{OpenTime: 1760432400000, Open: 90500, High: 91200, Low: 90100, Close: 91100, Volume: 8.25}
+	if klines[1] != expected {
+		t.Errorf("expected %+v, got %+v", expected, klines[1])
+	}
+
+	_, err = client.GetKlines(context.Background(), btcusdc, 10*time.Minute, 2)
+	if !errors.Is(err, exchanges.ErrInvalidParameter) {
+		t.Errorf("expected an invalid parameter for 10m klines, got %v", err)
+	}
+}
+
+func TestSignatureRejected(t *testing.T) {
+	bad := &Client{APIKey: "key", APISecret: "wrong", BaseURL: client.BaseURL}
+
+	_, err := bad.GetBalance(context.Background(), "USDC")
+	if !errors.Is(err, exchanges.ErrAuth) {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+}
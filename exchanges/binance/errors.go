@@ -0,0 +1,50 @@
+package binance
+
+import (
+	"github.com/buger/jsonparser"
+	"main/exchanges"
+	"strconv"
+	"strings"
+)
+
+// Error codes of the Binance API by kind
+var errorKinds = map[string]error{
+	"-1000": exchanges.ErrNetwork,          // Unknown error while processing the request
+	"-1001": exchanges.ErrNetwork,          // Internal error, unable to process
+	"-1003": exchanges.ErrRateLimited,      // Too many requests
+	"-1007": exchanges.ErrNetwork,          // Timeout waiting for the backend
+	"-1015": exchanges.ErrRateLimited,      // Too many new orders
+	"-1013": exchanges.ErrInvalidParameter, // Filter failure
+	"-1022": exchanges.ErrAuth,             // Signature for this request is not valid
+	"-2011": exchanges.ErrOrderNotFound,    // Unknown order sent
+	"-2013": exchanges.ErrOrderNotFound,    // Order does not exist
+	"-2014": exchanges.ErrAuth,             // API-key format invalid
+	"-2015": exchanges.ErrAuth,             // Invalid API-key, IP, or permissions
+}
+
+// apiError reads the {"code":..., "msg":...} body of a failed request
+func apiError(statusCode int, body []byte) *exchanges.APIError {
+	apiErr := &exchanges.APIError{Exchange: "Binance", StatusCode: statusCode, Message: string(body)}
+
+	code, err := jsonparser.GetInt(body, "code")
+	if err == nil {
+		apiErr.Code = strconv.FormatInt(code, 10)
+		apiErr.Message, _ = jsonparser.GetString(body, "msg")
+	}
+
+	kind, ok := errorKinds[apiErr.Code]
+	switch {
+	case ok:
+	// -2010 is any rejected order, only the message tells why
+	case code == -2010 && strings.Contains(strings.ToLower(apiErr.Message), "insufficient balance"):
+		kind = exchanges.ErrInsufficientBalance
+	// -1100 to -1199 are malformed requests
+	case code <= -1100 && code >= -1199:
+		kind = exchanges.ErrInvalidParameter
+	default:
+		kind = exchanges.StatusKind(statusCode)
+	}
+	apiErr.Kind = kind
+
+	return apiErr
+}
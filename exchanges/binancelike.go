@@ -0,0 +1,155 @@
+package exchanges
+
+import (
+	"fmt"
+	"github.com/buger/jsonparser"
+	"strconv"
+)
+
+// Binance and MEXC share the responses of the /api/v3 endpoints, they only
+// name some order statuses differently
+
+// ParseBinanceOrder parses an order as returned by the /api/v3/order
+// endpoints, status maps the status names of the exchange. The creation
+// response may have no status, the order is then NEW.
+func ParseBinanceOrder(symbol Symbol, body []byte, status func(string) OrderStatus) (*Order, error) {
+	id, _, _, err := jsonparser.Get(body, "orderId")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse orderId: %w", err)
+	}
+
+	order := &Order{Id: string(id), Symbol: symbol, Status: OrderNew}
+
+	if str, err := jsonparser.GetString(body, "status"); err == nil {
+		order.Status = status(str)
+	}
+	order.Side, _ = jsonparser.GetString(body, "side")
+
+	// A cancel response carries the original client id apart
+	order.ClientId, _ = jsonparser.GetString(body, "origClientOrderId")
+	if order.ClientId == "" {
+		order.ClientId, _ = jsonparser.GetString(body, "clientOrderId")
+	}
+
+	for key, field := range map[string]*float64{
+		"price":               &order.Price,
+		"origQty":             &order.OrigQty,
+		"executedQty":         &order.ExecutedQty,
+		"cummulativeQuoteQty": &order.QuoteQty,
+	} {
+		str, err := jsonparser.GetString(body, key)
+		if err != nil {
+			continue
+		}
+		*field, err = strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+	}
+
+	order.CreatedAt, _ = jsonparser.GetInt(body, "time")
+	if order.CreatedAt == 0 {
+		order.CreatedAt, _ = jsonparser.GetInt(body, "transactTime")
+	}
+	order.UpdatedAt, _ = jsonparser.GetInt(body, "updateTime")
+
+	return order, nil
+}
+
+// ParseBinanceOrders parses the array of /api/v3/openOrders
+func ParseBinanceOrders(symbol Symbol, body []byte, status func(string) OrderStatus) ([]Order, error) {
+	orders := []Order{}
+	var parseErr error
+	_, err := jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		order, err := ParseBinanceOrder(symbol, value, status)
+		if err != nil {
+			parseErr = err
+			return
+		}
+		orders = append(orders, *order)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing open orders: %v", err)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("error parsing open orders: %v", parseErr)
+	}
+
+	return orders, nil
+}
+
+// ParseBinanceTrades parses the fills of orderID as returned by
+// /api/v3/myTrades
+func ParseBinanceTrades(orderID string, body []byte) ([]Trade, error) {
+	trades := []Trade{}
+	var parseErr error
+	_, err := jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		id, _, _, _ := jsonparser.Get(value, "id")
+		trade := Trade{Id: string(id), OrderId: orderID}
+		trade.FeeAsset, _ = jsonparser.GetString(value, "commissionAsset")
+		trade.Time, _ = jsonparser.GetInt(value, "time")
+
+		for key, field := range map[string]*float64{
+			"price":      &trade.Price,
+			"qty":        &trade.Qty,
+			"quoteQty":   &trade.QuoteQty,
+			"commission": &trade.Fee,
+		} {
+			str, err := jsonparser.GetString(value, key)
+			if err != nil {
+				continue
+			}
+			*field, err = strconv.ParseFloat(str, 64)
+			if err != nil {
+				parseErr = fmt.Errorf("failed to parse %s: %w", key, err)
+			}
+		}
+		trades = append(trades, trade)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing trades: %v", err)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("error parsing trades: %v", parseErr)
+	}
+
+	return trades, nil
+}
+
+// ParseBinanceKlines parses klines like [[1640804880000,"47482.36","47482.36","47416.57","47436.1","3.550717",1640804940000,"168387.3"]],
+// open time, open, high, low, close, volume...
+func ParseBinanceKlines(body []byte) ([]Kline, error) {
+	var klines []Kline
+	var parseErr error
+	_, err := jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if parseErr != nil {
+			return
+		}
+
+		var kline Kline
+		kline.OpenTime, parseErr = jsonparser.GetInt(value, "[0]")
+		if parseErr != nil {
+			return
+		}
+
+		for i, field := range []*float64{&kline.Open, &kline.High, &kline.Low, &kline.Close, &kline.Volume} {
+			str, err := jsonparser.GetString(value, fmt.Sprintf("[%d]", i+1))
+			if err == nil {
+				*field, err = strconv.ParseFloat(str, 64)
+			}
+			if err != nil {
+				parseErr = err
+				return
+			}
+		}
+		klines = append(klines, kline)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing klines: %v", err)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("error parsing klines: %v", parseErr)
+	}
+
+	return klines, nil
+}
@@ -0,0 +1,56 @@
+package exchanges
+
+import "testing"
+
+func TestParseBinanceOrder(t *testing.T) {
+	status := func(str string) OrderStatus {
+		if str == "PENDING_CANCEL" {
+			return OrderCanceled
+		}
+		return OrderStatus(str)
+	}
+
+	order, err := ParseBinanceOrder(DefaultSymbol, []byte(`{"orderId":12,"status":"PENDING_CANCEL","side":"BUY","origClientOrderId":"C1","price":"90000.00","origQty":"0.001","executedQty":"0.0004","cummulativeQuoteQty":"36.00","time":1760432400000}`), status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Id != "12" || order.Status != OrderCanceled || order.ClientId != "C1" || order.ExecutedQty != 0.0004 || order.QuoteQty != 36 || order.CreatedAt != 1760432400000 {
+		t.Errorf("unexpected order %+v", order)
+	}
+
+	// A creation response has no status
+	order, err = ParseBinanceOrder(DefaultSymbol, []byte(`{"orderId":13,"transactTime":1760432400000}`), status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != OrderNew || order.CreatedAt != 1760432400000 {
+		t.Errorf("expected a new order, got %+v", order)
+	}
+}
+
+func TestParseBinanceTrades(t *testing.T) {
+	trades, err := ParseBinanceTrades("12", []byte(`[{"id":7,"price":"90000.00","qty":"0.0004","quoteQty":"36.00","commission":"0.036","commissionAsset":"USDC","time":1760432400000}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Trade{Id: "7", OrderId: "12", Price: 90000, Qty: 0.0004, QuoteQty: 36, Fee: 0.036, FeeAsset: "USDC", Time: 1760432400000}
+	if len(trades) != 1 || trades[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, trades)
+	}
+}
+
+func TestParseBinanceKlines(t *testing.T) {
+	klines, err := ParseBinanceKlines([]byte(`[[1760432400000,"90500.00","91200.00","90100.00","91100.00","8.25",1760435999999,"751575.00"]]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Kline{OpenTime: 1760432400000, Open: 90500, High: 91200, Low: 90100, Close: 91100, Volume: 8.25}
+	if len(klines) != 1 || klines[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, klines)
+	}
+
+	_, err = ParseBinanceKlines([]byte(`[[1760432400000,"90500.00","91200.00"]]`))
+	if err == nil {
+		t.Error("expected an error with a short kline")
+	}
+}
@@ -0,0 +1,7 @@
+package exchanges
+
+import "time"
+
+// CallTimeout bounds a call to an exchange, retries included, so a hung
+// request can not block the bot. An earlier deadline of the caller wins.
+var CallTimeout = 30 * time.Second
@@ -0,0 +1,67 @@
+package exchanges
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kinds of failure shared by every exchange client, to be tested with
+// errors.Is on the errors they return
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrAuth                = errors.New("authentication failed")
+	ErrNetwork             = errors.New("network error")
+	ErrInvalidParameter    = errors.New("invalid parameter")
+)
+
+// APIError is an error answered by an exchange. Kind is one of the Err
+// values above, nil when the error is not classified.
+type APIError struct {
+	Exchange   string
+	StatusCode int
+	Code       string
+	Message    string
+	Kind       error
+}
+
+func (e *APIError) Error() string {
+	str := fmt.Sprintf("%s error: HTTP status %d", e.Exchange, e.StatusCode)
+	if e.Code != "" {
+		str += " - code " + e.Code
+	}
+	if e.Message != "" {
+		str += " - " + e.Message
+	}
+	return str
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// NetworkError wraps a request that did not get an answer from the exchange
+func NetworkError(exchange string, err error) error {
+	return fmt.Errorf("%s %w: %w", exchange, ErrNetwork, err)
+}
+
+// StatusKind returns the kind implied by an HTTP status alone, for answers
+// without an error code the client knows
+func StatusKind(statusCode int) error {
+	switch {
+	case statusCode == http.StatusTooManyRequests || statusCode == http.StatusTeapot:
+		return ErrRateLimited
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrAuth
+	case statusCode >= 500:
+		return ErrNetwork
+	}
+	return nil
+}
+
+// Temporary reports whether the same call may succeed later
+func Temporary(err error) bool {
+	return errors.Is(err, ErrNetwork) || errors.Is(err, ErrRateLimited)
+}
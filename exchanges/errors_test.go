@@ -0,0 +1,45 @@
+package exchanges
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	err := fmt.Errorf("error getting order: %w", &APIError{
+		Exchange:   "MEXC",
+		StatusCode: 400,
+		Code:       "-2013",
+		Message:    "Order does not exist.",
+		Kind:       ErrOrderNotFound,
+	})
+
+	if !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected %v to be an order not found error", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "-2013" {
+		t.Errorf("expected the API error with its code, got %v", err)
+	}
+
+	expected := "error getting order: MEXC error: HTTP status 400 - code -2013 - Order does not exist."
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestTemporary(t *testing.T) {
+	for err, expected := range map[error]bool{
+		NetworkError("MEXC", errors.New("connection reset")):   true,
+		&APIError{StatusCode: 429, Kind: StatusKind(429)}:      true,
+		&APIError{StatusCode: 503, Kind: StatusKind(503)}:      true,
+		&APIError{StatusCode: 401, Kind: StatusKind(401)}:      false,
+		fmt.Errorf("order failed: %w", ErrInsufficientBalance): false,
+	} {
+		if Temporary(err) != expected {
+			t.Errorf("%v: expected temporary=%v", err, expected)
+		}
+	}
+}
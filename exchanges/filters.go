@@ -0,0 +1,84 @@
+package exchanges
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// SymbolFilters are the precision rules an exchange applies to the orders of
+// a symbol. A zero value means the exchange does not enforce the rule.
+type SymbolFilters struct {
+	TickSize    float64 // price increment
+	StepSize    float64 // quantity increment
+	MinQty      float64 // minimum quantity
+	MinNotional float64 // minimum price * quantity, in quote asset
+}
+
+// Without filters prices are formatted with 2 decimals and quantities with 6,
+// like the bot always did
+const (
+	defaultPriceDecimals    = 2
+	defaultQuantityDecimals = 6
+)
+
+// Number of decimals of an increment like 0.001
+func decimals(increment float64, fallback int) int {
+	if increment <= 0 {
+		return fallback
+	}
+
+	str := strconv.FormatFloat(increment, 'f', -1, 64)
+	for i := 0; i < len(str); i++ {
+		if str[i] == '.' {
+			return len(str) - i - 1
+		}
+	}
+	return 0
+}
+
+// Rounds value down to a multiple of increment. The epsilon absorbs float
+// errors like 0.3 / 0.1 = 2.9999999999999996.
+func floorTo(value, increment float64) float64 {
+	if increment <= 0 {
+		return value
+	}
+	return math.Floor(value/increment+1e-9) * increment
+}
+
+// RoundPrice rounds price to the nearest tick
+func (f SymbolFilters) RoundPrice(price float64) float64 {
+	if f.TickSize <= 0 {
+		return price
+	}
+	return math.Round(price/f.TickSize) * f.TickSize
+}
+
+// RoundQuantity rounds quantity down to the step size, so an order never
+// costs more than the balance it was computed from
+func (f SymbolFilters) RoundQuantity(quantity float64) float64 {
+	return floorTo(quantity, f.StepSize)
+}
+
+func (f SymbolFilters) FormatPrice(price float64) string {
+	return strconv.FormatFloat(f.RoundPrice(price), 'f', decimals(f.TickSize, defaultPriceDecimals), 64)
+}
+
+func (f SymbolFilters) FormatQuantity(quantity float64) string {
+	return strconv.FormatFloat(f.RoundQuantity(quantity), 'f', decimals(f.StepSize, defaultQuantityDecimals), 64)
+}
+
+// Validate returns an error when an order of quantity at price would be
+// rejected by the exchange. Both values must already be rounded.
+func (f SymbolFilters) Validate(price, quantity float64) error {
+	if quantity <= 0 {
+		return fmt.Errorf("quantity %v rounds to zero", quantity)
+	}
+	if f.MinQty > 0 && quantity < f.MinQty {
+		return fmt.Errorf("quantity %v below minimum %v", quantity, f.MinQty)
+	}
+	if f.MinNotional > 0 && price*quantity < f.MinNotional {
+		return fmt.Errorf("order value %.2f below minimum notional %v", price*quantity, f.MinNotional)
+	}
+	return nil
+}
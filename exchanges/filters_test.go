@@ -0,0 +1,52 @@
+package exchanges
+
+import "testing"
+
+func TestSymbolFilters_Format(t *testing.T) {
+	filters := SymbolFilters{TickSize: 0.01, StepSize: 0.00001, MinQty: 0.00001, MinNotional: 5}
+
+	if price := filters.FormatPrice(91234.567); price != "91234.57" {
+		t.Errorf("expected 91234.57, got %s", price)
+	}
+	// Rounded down, never up
+	if quantity := filters.FormatQuantity(0.000129); quantity != "0.00012" {
+		t.Errorf("expected 0.00012, got %s", quantity)
+	}
+	if quantity := filters.FormatQuantity(0.3); quantity != "0.30000" {
+		t.Errorf("expected 0.30000, got %s", quantity)
+	}
+
+	tick := SymbolFilters{TickSize: 0.5, StepSize: 1}
+	if price := tick.FormatPrice(100.3); price != "100.5" {
+		t.Errorf("expected 100.5, got %s", price)
+	}
+	if quantity := tick.FormatQuantity(12.9); quantity != "12" {
+		t.Errorf("expected 12, got %s", quantity)
+	}
+
+	// No filters, the historical format
+	var none SymbolFilters
+	if price := none.FormatPrice(91234.567); price != "91234.57" {
+		t.Errorf("expected 91234.57, got %s", price)
+	}
+	if quantity := none.FormatQuantity(0.0001234567); quantity != "0.000123" {
+		t.Errorf("expected 0.000123, got %s", quantity)
+	}
+}
+
+func TestSymbolFilters_Validate(t *testing.T) {
+	filters := SymbolFilters{TickSize: 0.01, StepSize: 0.00001, MinQty: 0.0001, MinNotional: 5}
+
+	if err := filters.Validate(90000, 0.0001); err != nil {
+		t.Errorf("expected a valid order, got %v", err)
+	}
+	if err := filters.Validate(90000, 0.00005); err == nil {
+		t.Error("expected an error below the minimum quantity")
+	}
+	if err := filters.Validate(40000, 0.0001); err == nil {
+		t.Error("expected an error below the minimum notional")
+	}
+	if err := filters.Validate(90000, 0); err == nil {
+		t.Error("expected an error for a zero quantity")
+	}
+}
@@ -0,0 +1,26 @@
+package exchanges
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kline is a candle of a symbol over one interval
+type Kline struct {
+	OpenTime int64   `json:"openTime"` // unix milliseconds
+	Open     float64 `json:"open"`
+	High     float64 `json:"high"`
+	Low      float64 `json:"low"`
+	Close    float64 `json:"close"`
+	Volume   float64 `json:"volume"`
+}
+
+// KlineInterval returns the name an exchange gives to interval, from names
+// keyed by the intervals it supports
+func KlineInterval(exchange string, interval time.Duration, names map[time.Duration]string) (string, error) {
+	name, ok := names[interval]
+	if !ok {
+		return "", fmt.Errorf("%w: %s has no %s klines", ErrInvalidParameter, exchange, interval)
+	}
+	return name, nil
+}
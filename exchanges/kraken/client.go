@@ -0,0 +1,561 @@
+package kraken
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"github.com/buger/jsonparser"
+	"github.com/fatih/color"
+	"io"
+	"main/exchanges"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Client struct {
+	APIKey    string
+	APISecret string
+	BaseURL   string
+
+	nonceMu   sync.Mutex
+	lastNonce int64
+}
+
+func NewClient() *Client {
+	return &Client{
+		APIKey:    os.Getenv("KRAKEN_API_KEY"),
+		APISecret: os.Getenv("KRAKEN_SECRET_KEY"),
+		BaseURL:   "https://api.kraken.com",
+	}
+}
+
+func (c *Client) SetBaseURL(url string) {
+	c.BaseURL = url
+}
+
+// Kraken names bitcoin XBT
+func krakenAsset(asset string) string {
+	if asset == "BTC" {
+		return "XBT"
+	}
+	return asset
+}
+
+// Returns the Kraken pair name of symbol, e.g. XBTUSDC for BTC/USDC
+func krakenPair(symbol exchanges.Symbol) string {
+	return krakenAsset(symbol.Base) + krakenAsset(symbol.Quote)
+}
+
+// Returns a strictly increasing nonce, as required by private endpoints
+func (c *Client) nonce() string {
+	c.nonceMu.Lock()
+	defer c.nonceMu.Unlock()
+
+	n := time.Now().UnixNano()
+	if n <= c.lastNonce {
+		n = c.lastNonce + 1
+	}
+	c.lastNonce = n
+
+	return strconv.FormatInt(n, 10)
+}
+
+// Generates the API-Sign header: base64(HMAC-SHA512(path + SHA256(nonce + postData)))
+// keyed with the base64 decoded secret
+func (c *Client) signRequest(path, nonce, postData string) (string, error) {
+	secret, err := base64.StdEncoding.DecodeString(c.APISecret)
+	if err != nil {
+		return "", fmt.Errorf("invalid KRAKEN_SECRET_KEY: %v", err)
+	}
+
+	sha := sha256.Sum256([]byte(nonce + postData))
+
+	h := hmac.New(sha512.New, secret)
+	h.Write([]byte(path))
+	h.Write(sha[:])
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// Sends an HTTP request and returns the "result" field of the response
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, exchanges.NetworkError("Kraken", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(resp.StatusCode, []string{string(body)})
+	}
+
+	var messages []string
+	_, _ = jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		messages = append(messages, string(value))
+	}, "error")
+	if len(messages) > 0 {
+		return nil, apiError(resp.StatusCode, messages)
+	}
+
+	result, _, _, err := jsonparser.Get(body, "result")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response result: %w", err)
+	}
+
+	return result, nil
+}
+
+func (c *Client) sendPublicRequest(ctx context.Context, method string, params url.Values) ([]byte, error) {
+	fullURL := fmt.Sprintf("%s/0/public/%s?%s", c.BaseURL, method, params.Encode())
+
+	ctx, cancel := context.WithTimeout(ctx, exchanges.CallTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(req)
+}
+
+func (c *Client) sendPrivateRequest(ctx context.Context, method string, params url.Values) ([]byte, error) {
+	path := "/0/private/" + method
+
+	if params == nil {
+		params = url.Values{}
+	}
+	nonce := c.nonce()
+	params.Set("nonce", nonce)
+	postData := params.Encode()
+
+	signature, err := c.signRequest(path, nonce, postData)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, exchanges.CallTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+path, strings.NewReader(postData))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("API-Key", c.APIKey)
+	req.Header.Set("API-Sign", signature)
+
+	return c.do(req)
+}
+
+func (c *Client) CheckConnection(ctx context.Context) error {
+	body, err := c.sendPublicRequest(ctx, "SystemStatus", nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Kraken: %w", err)
+	}
+
+	// Maintenance or cancel only, orders are accepted again later
+	status, _ := jsonparser.GetString(body, "status")
+	if status != "online" {
+		return fmt.Errorf("kraken is not accepting orders, system status %s: %w", status, exchanges.ErrNetwork)
+	}
+
+	color.Green("Connected to Kraken API successfully")
+	fmt.Println("")
+	return nil
+}
+
+func (c *Client) GetBalance(ctx context.Context, asset string) (float64, error) {
+	color.Blue("Checking %s balance...", asset)
+
+	body, err := c.sendPrivateRequest(ctx, "BalanceEx", nil)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching balance: %w", err)
+	}
+
+	// Older assets are prefixed, X for crypto (XXBT, XETH) and Z for fiat (ZUSD)
+	name := krakenAsset(asset)
+	for _, key := range []string{name, "X" + name, "Z" + name} {
+		balanceStr, err := jsonparser.GetString(body, key, "balance")
+		if err != nil {
+			continue
+		}
+		holdStr, _ := jsonparser.GetString(body, key, "hold_trade")
+		balance, _ := strconv.ParseFloat(balanceStr, 64)
+		hold, _ := strconv.ParseFloat(holdStr, 64)
+
+		return balance - hold, nil
+	}
+
+	return 0, nil
+}
+
+func (c *Client) GetLastPrice(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	body, err := c.sendPublicRequest(ctx, "Ticker", url.Values{"pair": {krakenPair(symbol)}})
+	if err != nil {
+		return 0, fmt.Errorf("error fetching %s price: %w", symbol, err)
+	}
+
+	// The result is keyed by the Kraken pair name, which is not always the
+	// requested one (XETHZUSD for ETHUSD). "c" is the last trade closed, as
+	// [price, lot volume].
+	var priceStr string
+	err = jsonparser.ObjectEach(body, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
+		priceStr, err = jsonparser.GetString(value, "c", "[0]")
+		return err
+	})
+	if err != nil || priceStr == "" {
+		return 0, fmt.Errorf("error extracting price: %v", err)
+	}
+
+	price, err := strconv.ParseFloat(priceStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error converting price: %v", err)
+	}
+
+	return price, nil
+}
+
+// CreateOrder places a limit order. Kraken only answers with the txid, the
+// rest of the order is what was sent. clientId is sent as cl_ord_id, free
+// text of up to 18 characters.
+func (c *Client) CreateOrder(ctx context.Context, symbol exchanges.Symbol, side string, price, quantity, clientId string) (*exchanges.Order, error) {
+	params := url.Values{
+		"ordertype": {"limit"},
+		"type":      {strings.ToLower(side)},
+		"pair":      {krakenPair(symbol)},
+		"price":     {price},
+		"volume":    {quantity},
+	}
+	if clientId != "" {
+		params.Set("cl_ord_id", clientId)
+	}
+
+	body, err := c.sendPrivateRequest(ctx, "AddOrder", params)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	txid, err := jsonparser.GetString(body, "txid", "[0]")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse txid: %w", err)
+	}
+
+	order := &exchanges.Order{
+		Id:        txid,
+		ClientId:  clientId,
+		Symbol:    symbol,
+		Side:      strings.ToUpper(side),
+		Status:    exchanges.OrderNew,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	order.Price, _ = strconv.ParseFloat(price, 64)
+	order.OrigQty, _ = strconv.ParseFloat(quantity, 64)
+
+	return order, nil
+}
+
+// Kraken txids are unique across pairs, symbol is not sent
+func (c *Client) GetOrderById(ctx context.Context, symbol exchanges.Symbol, id string) (*exchanges.Order, error) {
+	body, err := c.sendPrivateRequest(ctx, "QueryOrders", url.Values{"txid": {id}})
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	order, _, _, err := jsonparser.Get(body, id)
+	if err != nil {
+		return nil, fmt.Errorf("order %s: %w", id, exchanges.ErrOrderNotFound)
+	}
+
+	return parseOrder(symbol, id, order)
+}
+
+// GetOrderByClientId looks for the cl_ord_id in the open orders, then in the
+// closed ones
+func (c *Client) GetOrderByClientId(ctx context.Context, symbol exchanges.Symbol, clientId string) (*exchanges.Order, error) {
+	for _, method := range []string{"OpenOrders", "ClosedOrders"} {
+		body, err := c.sendPrivateRequest(ctx, method, url.Values{"cl_ord_id": {clientId}})
+		if err != nil {
+			return nil, fmt.Errorf("error sending request: %w", err)
+		}
+
+		// Checked again, the whole list comes back if the filter is ignored
+		var order *exchanges.Order
+		err = jsonparser.ObjectEach(body, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
+			if id, _ := jsonparser.GetString(value, "cl_ord_id"); id != clientId {
+				return nil
+			}
+			var err error
+			order, err = parseOrder(symbol, string(key), value)
+			return err
+		}, strings.ToLower(strings.TrimSuffix(method, "Orders")))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", method, err)
+		}
+		if order != nil {
+			return order, nil
+		}
+	}
+
+	return nil, fmt.Errorf("order %s: %w", clientId, exchanges.ErrOrderNotFound)
+}
+
+// Kraken answers with the number of canceled orders only, the order is read
+// again
+func (c *Client) CancelOrder(ctx context.Context, symbol exchanges.Symbol, orderID string) (*exchanges.Order, error) {
+	_, err := c.sendPrivateRequest(ctx, "CancelOrder", url.Values{"txid": {orderID}})
+	if err != nil {
+		return nil, fmt.Errorf("error canceling order %s: %w", orderID, err)
+	}
+
+	color.Green("Order %s canceled successfully", orderID)
+	return c.GetOrderById(ctx, symbol, orderID)
+}
+
+// GetOpenOrders returns the open orders of symbol only, Kraken lists every pair
+func (c *Client) GetOpenOrders(ctx context.Context, symbol exchanges.Symbol) ([]exchanges.Order, error) {
+	body, err := c.sendPrivateRequest(ctx, "OpenOrders", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching open orders: %w", err)
+	}
+
+	orders := []exchanges.Order{}
+	err = jsonparser.ObjectEach(body, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
+		orderPair, _ := jsonparser.GetString(value, "descr", "pair")
+		if orderPair != krakenPair(symbol) {
+			return nil
+		}
+
+		order, err := parseOrder(symbol, string(key), value)
+		if err != nil {
+			return err
+		}
+		orders = append(orders, *order)
+		return nil
+	}, "open")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching open orders: %v", err)
+	}
+
+	return orders, nil
+}
+
+// GetOrderTrades returns the execution of an order as a single trade. Kraken
+// orders carry their total cost and fee, which spares a QueryTrades call.
+func (c *Client) GetOrderTrades(ctx context.Context, symbol exchanges.Symbol, orderID string) ([]exchanges.Trade, error) {
+	order, err := c.GetOrderById(ctx, symbol, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.ExecutedQty == 0 {
+		return []exchanges.Trade{}, nil
+	}
+
+	return []exchanges.Trade{{
+		OrderId:  orderID,
+		Price:    order.QuoteQty / order.ExecutedQty,
+		Qty:      order.ExecutedQty,
+		QuoteQty: order.QuoteQty,
+		Fee:      order.Fee,
+		FeeAsset: order.FeeAsset,
+		Time:     order.UpdatedAt,
+	}}, nil
+}
+
+// GetSymbolFilters reads the precision rules of symbol from AssetPairs.
+// Kraken gives the quantity precision as lot_decimals.
+func (c *Client) GetSymbolFilters(ctx context.Context, symbol exchanges.Symbol) (exchanges.SymbolFilters, error) {
+	var filters exchanges.SymbolFilters
+
+	body, err := c.sendPublicRequest(ctx, "AssetPairs", url.Values{"pair": {krakenPair(symbol)}})
+	if err != nil {
+		return filters, fmt.Errorf("error fetching asset pair %s: %w", symbol, err)
+	}
+
+	// Keyed by the Kraken pair name, like Ticker
+	var pair []byte
+	err = jsonparser.ObjectEach(body, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
+		pair = value
+		return nil
+	})
+	if err != nil || pair == nil {
+		return filters, fmt.Errorf("asset pair %s not found: %v", symbol, err)
+	}
+
+	lotDecimals, err := jsonparser.GetInt(pair, "lot_decimals")
+	if err != nil {
+		return filters, fmt.Errorf("error extracting lot_decimals: %v", err)
+	}
+	filters.StepSize = math.Pow10(-int(lotDecimals))
+
+	for key, field := range map[string]*float64{
+		"tick_size": &filters.TickSize,
+		"ordermin":  &filters.MinQty,
+		"costmin":   &filters.MinNotional,
+	} {
+		str, err := jsonparser.GetString(pair, key)
+		if err != nil {
+			continue
+		}
+		*field, err = strconv.ParseFloat(str, 64)
+		if err != nil {
+			return filters, fmt.Errorf("error converting %s: %v", key, err)
+		}
+	}
+
+	return filters, nil
+}
+
+// Kline intervals of Kraken, in minutes
+var klineIntervals = map[time.Duration]string{
+	time.Minute:         "1",
+	5 * time.Minute:     "5",
+	15 * time.Minute:    "15",
+	30 * time.Minute:    "30",
+	time.Hour:           "60",
+	4 * time.Hour:       "240",
+	24 * time.Hour:      "1440",
+	7 * 24 * time.Hour:  "10080",
+	15 * 24 * time.Hour: "21600",
+}
+
+// GetKlines returns the last limit klines of symbol from OHLC, oldest
+// first. The last one is still open.
+func (c *Client) GetKlines(ctx context.Context, symbol exchanges.Symbol, interval time.Duration, limit int) ([]exchanges.Kline, error) {
+	name, err := exchanges.KlineInterval("Kraken", interval, klineIntervals)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.sendPublicRequest(ctx, "OHLC", url.Values{"pair": {krakenPair(symbol)}, "interval": {name}})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s klines: %w", symbol, err)
+	}
+
+	// Keyed by the Kraken pair name, like Ticker, next to "last". Each
+	// kline is [time, open, high, low, close, vwap, volume, count], with
+	// the time in seconds.
+	var klines []exchanges.Kline
+	err = jsonparser.ObjectEach(body, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
+		if dataType != jsonparser.Array {
+			return nil
+		}
+
+		var parseErr error
+		_, err := jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+			if parseErr != nil {
+				return
+			}
+
+			var kline exchanges.Kline
+			openTime, err := jsonparser.GetInt(value, "[0]")
+			if err != nil {
+				parseErr = err
+				return
+			}
+			kline.OpenTime = openTime * 1000
+
+			for i, field := range map[int]*float64{1: &kline.Open, 2: &kline.High, 3: &kline.Low, 4: &kline.Close, 6: &kline.Volume} {
+				str, err := jsonparser.GetString(value, fmt.Sprintf("[%d]", i))
+				if err == nil {
+					*field, err = strconv.ParseFloat(str, 64)
+				}
+				if err != nil {
+					parseErr = err
+					return
+				}
+			}
+			klines = append(klines, kline)
+		})
+		if err != nil {
+			return err
+		}
+		return parseErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing klines: %v", err)
+	}
+
+	// Kraken answers up to 720 klines
+	if len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+	return klines, nil
+}
+
+// Parses a Kraken order, keyed by its txid in every response. Limit orders
+// only close once fully executed, otherwise they are "canceled" or "expired".
+func parseOrder(symbol exchanges.Symbol, txid string, body []byte) (*exchanges.Order, error) {
+	status, err := jsonparser.GetString(body, "status")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse order status: %w", err)
+	}
+
+	// Fees are charged in the quote currency
+	order := &exchanges.Order{Id: txid, Symbol: symbol, FeeAsset: symbol.Quote}
+	order.ClientId, _ = jsonparser.GetString(body, "cl_ord_id")
+	side, _ := jsonparser.GetString(body, "descr", "type")
+	order.Side = strings.ToUpper(side)
+
+	// "price" is the average execution price, the limit is in descr
+	for key, field := range map[string]*float64{
+		"vol":      &order.OrigQty,
+		"vol_exec": &order.ExecutedQty,
+		"cost":     &order.QuoteQty,
+		"fee":      &order.Fee,
+	} {
+		str, err := jsonparser.GetString(body, key)
+		if err != nil {
+			continue
+		}
+		*field, err = strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+	}
+	if str, err := jsonparser.GetString(body, "descr", "price"); err == nil {
+		order.Price, err = strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse descr.price: %w", err)
+		}
+	}
+
+	// Times are seconds as floats
+	opentm, _ := jsonparser.GetFloat(body, "opentm")
+	closetm, _ := jsonparser.GetFloat(body, "closetm")
+	order.CreatedAt = int64(opentm * 1000)
+	order.UpdatedAt = int64(closetm * 1000)
+
+	switch status {
+	case "closed":
+		order.Status = exchanges.OrderFilled
+	case "canceled":
+		order.Status = exchanges.OrderCanceled
+	case "expired":
+		order.Status = exchanges.OrderExpired
+	default: // pending, open
+		order.Status = exchanges.OrderNew
+		if order.ExecutedQty > 0 {
+			order.Status = exchanges.OrderPartiallyFilled
+		}
+	}
+
+	return order, nil
+}
@@ -0,0 +1,307 @@
+package kraken
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/buger/jsonparser"
+	"io"
+	"main/exchanges"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var client *Client
+
+var btcusdc = exchanges.DefaultSymbol
+
+// fakeKraken serves the recorded responses of testdata/<Method>.json.
+// Private calls must be correctly signed and QueryOrders only returns
+// the requested txid, like the real API.
+func fakeKraken(t *testing.T) *httptest.Server {
+	fixture := func(name string) []byte {
+		content, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+		if err != nil {
+			t.Fatalf("missing fixture %s: %v", name, err)
+		}
+		return content
+	}
+
+	// Canceled orders read back canceled
+	canceled := map[string]bool{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+		if strings.HasPrefix(r.URL.Path, "/0/private/") {
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(strings.NewReader(string(body)))
+			_ = r.ParseForm()
+
+			expected, _ := client.signRequest(r.URL.Path, r.PostForm.Get("nonce"), string(body))
+			if r.Method != "POST" || r.Header.Get("API-Key") != client.APIKey || r.Header.Get("API-Sign") != expected {
+				_, _ = w.Write(fixture("invalid_signature"))
+				return
+			}
+		}
+
+		if method == "QueryOrders" {
+			txid := r.PostForm.Get("txid")
+			order, _, _, err := jsonparser.Get(fixture(method), "result", txid)
+			if err != nil {
+				_, _ = w.Write(fixture("QueryOrders_invalid"))
+				return
+			}
+			if canceled[txid] {
+				order = []byte(strings.Replace(string(order), `"status":"open"`, `"status":"canceled"`, 1))
+			}
+			_, _ = io.WriteString(w, `{"error":[],"result":{"`+txid+`":`+string(order)+`}}`)
+			return
+		}
+		if method == "CancelOrder" {
+			canceled[r.PostForm.Get("txid")] = true
+		}
+
+		if _, err := os.Stat(filepath.Join("testdata", method+".json")); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(fixture(method))
+	}))
+}
+
+func TestMain(m *testing.M) {
+	client = &Client{
+		APIKey:    "key",
+		APISecret: base64.StdEncoding.EncodeToString([]byte("secret")),
+	}
+
+	os.Exit(m.Run())
+}
+
+func setup(t *testing.T) {
+	server := fakeKraken(t)
+	client.SetBaseURL(server.URL)
+	t.Cleanup(server.Close)
+}
+
+func TestCheckConnection(t *testing.T) {
+	setup(t)
+	err := client.CheckConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	setup(t)
+
+	balance, err := client.GetBalance(context.Background(), "USDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 300.25 {
+		t.Errorf("expected free balance 300.25, got %v", balance)
+	}
+
+	// Listed as XXBT
+	balance, err = client.GetBalance(context.Background(), "BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 0.002 {
+		t.Errorf("expected free BTC balance 0.002, got %v", balance)
+	}
+}
+
+func TestGetLastPrice(t *testing.T) {
+	setup(t)
+
+	price, err := client.GetLastPrice(context.Background(), btcusdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != 91234.5 {
+		t.Errorf("expected price 91234.5, got %v", price)
+	}
+}
+
+func TestGetKlines(t *testing.T) {
+	setup(t)
+
+	klines, err := client.GetKlines(context.Background(), btcusdc, time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(klines) != 2 {
+		t.Fatalf("expected the last 2 klines, got %d", len(klines))
+	}
+	expected := exchanges.Kline{OpenTime: 1760432400000, Open: 90500, High: 91200, Low: 90100, Close: 91100, Volume: 8.25}
+	if klines[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, klines[0])
+	}
+}
+
+func TestCreateOrder(t *testing.T) {
+	setup(t)
+
+	order, err := client.CreateOrder(context.Background(), btcusdc, "BUY", "90000", "0.0001", "bs-1a2b-7-buy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Id != "OUF4EM-FRGI2-MQMWZD" || order.Status != exchanges.OrderNew {
+		t.Errorf("expected new order OUF4EM-FRGI2-MQMWZD, got %+v", order)
+	}
+}
+
+func TestGetOrderById(t *testing.T) {
+	setup(t)
+
+	order, err := client.GetOrderById(context.Background(), btcusdc, "OB5VMB-B4U2U-DK2WRW")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Side != "SELL" || order.Price != 95000 || order.OrigQty != 0.0001 {
+		t.Errorf("expected SELL 0.0001 at 95000, got %+v", order)
+	}
+
+	_, err = client.GetOrderById(context.Background(), btcusdc, "OXXXXX-XXXXX-XXXXXX")
+	if !errors.Is(err, exchanges.ErrOrderNotFound) {
+		t.Errorf("expected an order not found error, got %v", err)
+	}
+}
+
+func TestIsFilled(t *testing.T) {
+	setup(t)
+
+	for id, expected := range map[string]bool{"OQCLML-BW3P3-BUCMWZ": true, "OB5VMB-B4U2U-DK2WRW": false} {
+		order, err := client.GetOrderById(context.Background(), btcusdc, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if order.IsFilled() != expected {
+			t.Errorf("%s: expected filled=%v, got %v", id, expected, order.IsFilled())
+		}
+	}
+}
+
+func TestGetOrderById_Filled(t *testing.T) {
+	setup(t)
+
+	order, err := client.GetOrderById(context.Background(), btcusdc, "OQCLML-BW3P3-BUCMWZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.ExecutedQty != 0.0001 || order.QuoteQty != 9 || order.Fee != 0.0234 || order.FeeAsset != "USDC" {
+		t.Errorf("unexpected execution %+v", order)
+	}
+	if order.CreatedAt != 1760432460123 || order.UpdatedAt != 1760436060567 {
+		t.Errorf("unexpected times %d %d", order.CreatedAt, order.UpdatedAt)
+	}
+}
+
+func TestGetOrderTrades(t *testing.T) {
+	setup(t)
+
+	trades, err := client.GetOrderTrades(context.Background(), btcusdc, "OQCLML-BW3P3-BUCMWZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || trades[0].Price != 90000 || trades[0].Fee != 0.0234 || trades[0].FeeAsset != "USDC" {
+		t.Errorf("unexpected trades %+v", trades)
+	}
+
+	// Nothing executed, no trade
+	trades, err = client.GetOrderTrades(context.Background(), btcusdc, "OB5VMB-B4U2U-DK2WRW")
+	if err != nil || len(trades) != 0 {
+		t.Errorf("expected no trade, got %+v %v", trades, err)
+	}
+}
+
+func TestGetOrderByClientId(t *testing.T) {
+	setup(t)
+
+	order, err := client.GetOrderByClientId(context.Background(), btcusdc, "bs-1a2b-7-sell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Id != "OB5VMB-B4U2U-DK2WRW" || order.ClientId != "bs-1a2b-7-sell" {
+		t.Errorf("expected open order OB5VMB-B4U2U-DK2WRW, got %+v", order)
+	}
+
+	_, err = client.GetOrderByClientId(context.Background(), btcusdc, "bs-1a2b-8-buy")
+	if !errors.Is(err, exchanges.ErrOrderNotFound) {
+		t.Errorf("expected an order not found error, got %v", err)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	setup(t)
+
+	order, err := client.CancelOrder(context.Background(), btcusdc, "OB5VMB-B4U2U-DK2WRW")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Status != exchanges.OrderCanceled {
+		t.Errorf("expected status CANCELED, got %s", order.Status)
+	}
+	if order.Id != "OB5VMB-B4U2U-DK2WRW" || order.OrigQty != 0.0001 || order.Price != 95000 || order.Side != "SELL" {
+		t.Errorf("expected the canceled order in full, got %+v", order)
+	}
+}
+
+func TestGetOpenOrders(t *testing.T) {
+	setup(t)
+
+	orders, err := client.GetOpenOrders(context.Background(), btcusdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ETHUSDC order is filtered out
+	if len(orders) != 1 || orders[0].Id != "OB5VMB-B4U2U-DK2WRW" {
+		t.Errorf("expected only the XBTUSDC order in open orders, got %+v", orders)
+	}
+}
+
+func TestGetSymbolFilters(t *testing.T) {
+	setup(t)
+
+	filters, err := client.GetSymbolFilters(context.Background(), btcusdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := exchanges.SymbolFilters{TickSize: 0.01, StepSize: 0.00000001, MinQty: 0.00005, MinNotional: 0.5}
+	if filters != expected {
+		t.Errorf("expected %+v, got %+v", expected, filters)
+	}
+}
+
+func TestSignatureRejected(t *testing.T) {
+	setup(t)
+
+	bad := &Client{APIKey: "key", APISecret: base64.StdEncoding.EncodeToString([]byte("wrong")), BaseURL: client.BaseURL}
+
+	_, err := bad.GetBalance(context.Background(), "USDC")
+	if !errors.Is(err, exchanges.ErrAuth) || !strings.Contains(err.Error(), "EAPI:Invalid signature") {
+		t.Errorf("expected an invalid signature error, got %v", err)
+	}
+}
+
+func TestInvalidNonce(t *testing.T) {
+	err := apiError(200, []string{"EAPI:Invalid nonce"})
+	if errors.Is(err, exchanges.ErrAuth) || !exchanges.Temporary(err) {
+		t.Errorf("expected a temporary error, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package kraken
+
+import (
+	"main/exchanges"
+	"strings"
+)
+
+// Kraken errors are strings like "EOrder:Insufficient funds", by kind
+var errorKinds = map[string]error{
+	"EOrder:Insufficient funds":           exchanges.ErrInsufficientBalance,
+	"EOrder:Unknown order":                exchanges.ErrOrderNotFound,
+	"EOrder:Invalid order":                exchanges.ErrOrderNotFound, // QueryOrders with an unknown txid
+	"EAPI:Rate limit exceeded":            exchanges.ErrRateLimited,
+	"EOrder:Rate limit exceeded":          exchanges.ErrRateLimited,
+	"EGeneral:Too many requests":          exchanges.ErrRateLimited,
+	"EAPI:Invalid key":                    exchanges.ErrAuth,
+	"EAPI:Invalid signature":              exchanges.ErrAuth,
+	"EGeneral:Permission denied":          exchanges.ErrAuth,
+	"EGeneral:Invalid arguments":          exchanges.ErrInvalidParameter,
+	"EQuery:Unknown asset pair":           exchanges.ErrInvalidParameter,
+	"EOrder:Invalid price":                exchanges.ErrInvalidParameter,
+	"EOrder:Order minimum not met":        exchanges.ErrInvalidParameter,
+	"EOrder:Cost minimum not met":         exchanges.ErrInvalidParameter,
+	"EOrder:Tick size check failed":       exchanges.ErrInvalidParameter,
+	"EService:Unavailable":                exchanges.ErrNetwork,
+	"EService:Busy":                       exchanges.ErrNetwork,
+	"EService:Market in cancel_only mode": exchanges.ErrNetwork,
+	"EGeneral:Internal error":             exchanges.ErrNetwork,
+	"EAPI:Invalid nonce":                  exchanges.ErrNetwork, // two requests signed in the same instant, the next one passes
+}
+
+// apiError builds the error of a response carrying error messages. The
+// first message Kraken classifies gives the kind.
+func apiError(statusCode int, messages []string) *exchanges.APIError {
+	apiErr := &exchanges.APIError{
+		Exchange:   "Kraken",
+		StatusCode: statusCode,
+		Message:    strings.Join(messages, ", "),
+		Kind:       exchanges.StatusKind(statusCode),
+	}
+
+	for _, message := range messages {
+		// Some messages carry details after a colon, "EGeneral:Invalid arguments:volume"
+		parts := strings.SplitN(message, ":", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		kind, ok := errorKinds[parts[0]+":"+parts[1]]
+		if ok {
+			apiErr.Code = parts[0] + ":" + parts[1]
+			apiErr.Kind = kind
+			break
+		}
+	}
+
+	return apiErr
+}
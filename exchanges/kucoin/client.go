@@ -1,55 +1,456 @@
 package kucoin
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/buger/jsonparser"
+	"github.com/fatih/color"
+	"io"
+	"main/exchanges"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Client struct {
-	APIKey    string
-	APISecret string
-	BaseURL   string
+	APIKey        string
+	APISecret     string
+	APIPassphrase string
+	BaseURL       string
 }
 
 func NewClient() *Client {
 	return &Client{
-		APIKey:    os.Getenv("MEXC_API_KEY"),
-		APISecret: os.Getenv("MEXC_SECRET_KEY"),
-		BaseURL:   "https://api.kucoin.com/api",
+		APIKey:        os.Getenv("KUCOIN_API_KEY"),
+		APISecret:     os.Getenv("KUCOIN_SECRET_KEY"),
+		APIPassphrase: os.Getenv("KUCOIN_PASSPHRASE"),
+		BaseURL:       "https://api.kucoin.com",
 	}
 }
 
-func (c *Client) sendRequest(method, endpoint, queryString string) /*([]byte, error)*/ {
+func (c *Client) SetBaseURL(url string) {
+	c.BaseURL = url
+}
 
+// Generates base64 HMAC SHA256 signature of payload with the API secret
+func (c *Client) sign(payload string) string {
+	h := hmac.New(sha256.New, []byte(c.APISecret))
+	h.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
-func (c *Client) SetBaseURL(url string) {
-	c.BaseURL = url
+// Sends an HTTP request and returns the "data" field of the response, within
+// exchanges.CallTimeout. KuCoin signs timestamp + method + endpoint (with
+// query string) + body, and API keys v2 expect the passphrase to be signed
+// as well.
+func (c *Client) sendRequest(ctx context.Context, method, endpoint string, body []byte) ([]byte, error) {
+	fullURL := c.BaseURL + endpoint
+
+	ctx, cancel := context.WithTimeout(ctx, exchanges.CallTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("KC-API-KEY", c.APIKey)
+	req.Header.Set("KC-API-SIGN", c.sign(timestamp+method+endpoint+string(body)))
+	req.Header.Set("KC-API-TIMESTAMP", timestamp)
+	req.Header.Set("KC-API-PASSPHRASE", c.sign(c.APIPassphrase))
+	req.Header.Set("KC-API-KEY-VERSION", "2")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, exchanges.NetworkError("KuCoin", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(resp.StatusCode, respBody)
+	}
+
+	code, err := jsonparser.GetString(respBody, "code")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response code: %w", err)
+	}
+	if code != "200000" {
+		return nil, apiError(resp.StatusCode, respBody)
+	}
+
+	data, _, _, err := jsonparser.Get(respBody, "data")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response data: %w", err)
+	}
+
+	return data, nil
+}
+
+func (c *Client) CheckConnection(ctx context.Context) error {
+	_, err := c.sendRequest(ctx, "GET", "/api/v1/timestamp", nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to KuCoin: %w", err)
+	}
+
+	color.Green("Connected to KuCoin API successfully")
+	fmt.Println("")
+	return nil
+}
+
+func (c *Client) GetBalance(ctx context.Context, asset string) (float64, error) {
+	color.Blue("Checking %s balance...", asset)
+
+	body, err := c.sendRequest(ctx, "GET", "/api/v1/accounts?currency="+asset+"&type=trade", nil)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching balance: %w", err)
+	}
+
+	var freeFloat float64
+	_, err = jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		availableStr, _ := jsonparser.GetString(value, "available")
+		available, _ := strconv.ParseFloat(availableStr, 64)
+		freeFloat += available
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error getting balances: %v", err)
+	}
+
+	return freeFloat, nil
+}
+
+func (c *Client) GetLastPrice(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	body, err := c.sendRequest(ctx, "GET", "/api/v1/market/orderbook/level1?symbol="+symbol.Join("-"), nil)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching %s price: %w", symbol, err)
+	}
+
+	priceStr, err := jsonparser.GetString(body, "price")
+	if err != nil {
+		return 0, fmt.Errorf("error extracting price: %v", err)
+	}
+
+	price, err := strconv.ParseFloat(priceStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error converting price: %v", err)
+	}
+
+	return price, nil
+}
+
+// CreateOrder places a limit order. KuCoin only answers with the order id,
+// the rest of the order is what was sent. KuCoin requires a clientOid, one
+// is generated when clientId is empty.
+func (c *Client) CreateOrder(ctx context.Context, symbol exchanges.Symbol, side string, price, quantity, clientId string) (*exchanges.Order, error) {
+	clientOid := clientId
+	if clientOid == "" {
+		clientOid = strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	payload, err := json.Marshal(map[string]string{
+		"clientOid": clientOid,
+		"side":      strings.ToLower(side),
+		"symbol":    symbol.Join("-"),
+		"type":      "limit",
+		"price":     price,
+		"size":      quantity,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.sendRequest(ctx, "POST", "/api/v1/orders", payload)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	id, err := jsonparser.GetString(body, "orderId")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse orderId: %w", err)
+	}
+
+	order := &exchanges.Order{
+		Id:        id,
+		ClientId:  clientOid,
+		Symbol:    symbol,
+		Side:      strings.ToUpper(side),
+		Status:    exchanges.OrderNew,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	order.Price, _ = strconv.ParseFloat(price, 64)
+	order.OrigQty, _ = strconv.ParseFloat(quantity, 64)
+
+	return order, nil
 }
 
-func (c *Client) CheckConnection() {
+// KuCoin order ids are unique across symbols, symbol is not sent
+func (c *Client) GetOrderById(ctx context.Context, symbol exchanges.Symbol, id string) (*exchanges.Order, error) {
+	body, err := c.sendRequest(ctx, "GET", "/api/v1/orders/"+id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
 
+	return parseOrder(symbol, body)
 }
 
-func (c *Client) GetBalanceUSD() /*float64*/ {
+func (c *Client) GetOrderByClientId(ctx context.Context, symbol exchanges.Symbol, clientId string) (*exchanges.Order, error) {
+	body, err := c.sendRequest(ctx, "GET", "/api/v1/order/client-order/"+url.PathEscape(clientId), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
 
+	return parseOrder(symbol, body)
 }
 
-func (c *Client) GetLastPriceBTC() /*float64*/ {
+// KuCoin answers with the canceled ids only, the order is read again
+func (c *Client) CancelOrder(ctx context.Context, symbol exchanges.Symbol, orderID string) (*exchanges.Order, error) {
+	_, err := c.sendRequest(ctx, "DELETE", "/api/v1/orders/"+orderID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error canceling order %s: %w", orderID, err)
+	}
 
+	color.Green("Order %s canceled successfully", orderID)
+	return c.GetOrderById(ctx, symbol, orderID)
 }
 
-func CreateOrder() {
+func (c *Client) GetOpenOrders(ctx context.Context, symbol exchanges.Symbol) ([]exchanges.Order, error) {
+	body, err := c.sendRequest(ctx, "GET", "/api/v1/orders?status=active&symbol="+symbol.Join("-"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching open orders: %w", err)
+	}
+
+	items, _, _, err := jsonparser.Get(body, "items")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching open orders: %v", err)
+	}
+
+	orders := []exchanges.Order{}
+	var parseErr error
+	_, err = jsonparser.ArrayEach(items, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		order, err := parseOrder(symbol, value)
+		if err != nil {
+			parseErr = err
+			return
+		}
+		orders = append(orders, *order)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing open orders: %v", err)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("error parsing open orders: %v", parseErr)
+	}
+
+	return orders, nil
+}
+
+// GetOrderTrades returns the fills of an order with their commission
+func (c *Client) GetOrderTrades(ctx context.Context, symbol exchanges.Symbol, orderID string) ([]exchanges.Trade, error) {
+	body, err := c.sendRequest(ctx, "GET", "/api/v1/fills?orderId="+orderID+"&pageSize=500", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching fills of order %s: %w", orderID, err)
+	}
+
+	items, _, _, err := jsonparser.Get(body, "items")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching fills of order %s: %v", orderID, err)
+	}
+
+	trades := []exchanges.Trade{}
+	var parseErr error
+	_, err = jsonparser.ArrayEach(items, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		trade := exchanges.Trade{OrderId: orderID}
+		trade.Id, _ = jsonparser.GetString(value, "tradeId")
+		trade.FeeAsset, _ = jsonparser.GetString(value, "feeCurrency")
+		trade.Time, _ = jsonparser.GetInt(value, "createdAt")
+
+		for key, field := range map[string]*float64{
+			"price": &trade.Price,
+			"size":  &trade.Qty,
+			"funds": &trade.QuoteQty,
+			"fee":   &trade.Fee,
+		} {
+			str, err := jsonparser.GetString(value, key)
+			if err != nil {
+				continue
+			}
+			*field, err = strconv.ParseFloat(str, 64)
+			if err != nil {
+				parseErr = fmt.Errorf("failed to parse %s: %w", key, err)
+			}
+		}
+		trades = append(trades, trade)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing fills: %v", err)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("error parsing fills: %v", parseErr)
+	}
 
+	return trades, nil
 }
 
-func GetOrderById() {
+func (c *Client) GetSymbolFilters(ctx context.Context, symbol exchanges.Symbol) (exchanges.SymbolFilters, error) {
+	var filters exchanges.SymbolFilters
+
+	body, err := c.sendRequest(ctx, "GET", "/api/v2/symbols/"+symbol.Join("-"), nil)
+	if err != nil {
+		return filters, fmt.Errorf("error fetching symbol %s: %w", symbol, err)
+	}
+
+	for key, field := range map[string]*float64{
+		"priceIncrement": &filters.TickSize,
+		"baseIncrement":  &filters.StepSize,
+		"baseMinSize":    &filters.MinQty,
+		"minFunds":       &filters.MinNotional,
+	} {
+		str, err := jsonparser.GetString(body, key)
+		if err != nil {
+			continue
+		}
+		*field, err = strconv.ParseFloat(str, 64)
+		if err != nil {
+			return filters, fmt.Errorf("error converting %s: %v", key, err)
+		}
+	}
+
+	return filters, nil
+}
 
+// Kline types of KuCoin
+var klineIntervals = map[time.Duration]string{
+	time.Minute:        "1min",
+	3 * time.Minute:    "3min",
+	5 * time.Minute:    "5min",
+	15 * time.Minute:   "15min",
+	30 * time.Minute:   "30min",
+	time.Hour:          "1hour",
+	2 * time.Hour:      "2hour",
+	4 * time.Hour:      "4hour",
+	6 * time.Hour:      "6hour",
+	8 * time.Hour:      "8hour",
+	12 * time.Hour:     "12hour",
+	24 * time.Hour:     "1day",
+	7 * 24 * time.Hour: "1week",
 }
 
-func IsFilled() {
+// GetKlines returns the last limit klines of symbol, oldest first. The last
+// one is still open.
+func (c *Client) GetKlines(ctx context.Context, symbol exchanges.Symbol, interval time.Duration, limit int) ([]exchanges.Kline, error) {
+	name, err := exchanges.KlineInterval("KuCoin", interval, klineIntervals)
+	if err != nil {
+		return nil, err
+	}
+
+	// No limit parameter, the range starts limit klines ago
+	startAt := time.Now().Add(-interval * time.Duration(limit)).Unix()
+	endpoint := fmt.Sprintf("/api/v1/market/candles?type=%s&symbol=%s&startAt=%d", name, symbol.Join("-"), startAt)
+	body, err := c.sendRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s klines: %w", symbol, err)
+	}
+
+	// Newest first, each kline is [time, open, close, high, low, volume,
+	// turnover] as strings, with the time in seconds
+	var klines []exchanges.Kline
+	var parseErr error
+	_, err = jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if parseErr != nil {
+			return
+		}
 
+		var kline exchanges.Kline
+		var openTime float64
+		for i, field := range []*float64{&openTime, &kline.Open, &kline.Close, &kline.High, &kline.Low, &kline.Volume} {
+			str, err := jsonparser.GetString(value, fmt.Sprintf("[%d]", i))
+			if err == nil {
+				*field, err = strconv.ParseFloat(str, 64)
+			}
+			if err != nil {
+				parseErr = err
+				return
+			}
+		}
+		kline.OpenTime = int64(openTime) * 1000
+		klines = append([]exchanges.Kline{kline}, klines...)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing klines: %v", err)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("error parsing klines: %v", parseErr)
+	}
+
+	if len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+	return klines, nil
 }
 
-func CancelOrder() {
+// Parses a KuCoin order. KuCoin has no status field: an order is active or
+// done, and a done order is filled when its whole size was dealt.
+func parseOrder(symbol exchanges.Symbol, body []byte) (*exchanges.Order, error) {
+	id, err := jsonparser.GetString(body, "id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse order id: %w", err)
+	}
+	isActive, err := jsonparser.GetBoolean(body, "isActive")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse order status: %w", err)
+	}
+
+	order := &exchanges.Order{Id: id, Symbol: symbol}
+	order.ClientId, _ = jsonparser.GetString(body, "clientOid")
+	side, _ := jsonparser.GetString(body, "side")
+	order.Side = strings.ToUpper(side)
+	order.FeeAsset, _ = jsonparser.GetString(body, "feeCurrency")
+	order.CreatedAt, _ = jsonparser.GetInt(body, "createdAt")
+
+	for key, field := range map[string]*float64{
+		"price":     &order.Price,
+		"size":      &order.OrigQty,
+		"dealSize":  &order.ExecutedQty,
+		"dealFunds": &order.QuoteQty,
+		"fee":       &order.Fee,
+	} {
+		str, err := jsonparser.GetString(body, key)
+		if err != nil {
+			continue
+		}
+		*field, err = strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+	}
+
+	switch {
+	case isActive && order.ExecutedQty > 0:
+		order.Status = exchanges.OrderPartiallyFilled
+	case isActive:
+		order.Status = exchanges.OrderNew
+	case order.OrigQty > 0 && order.ExecutedQty >= order.OrigQty:
+		order.Status = exchanges.OrderFilled
+	default:
+		order.Status = exchanges.OrderCanceled
+	}
 
+	return order, nil
 }
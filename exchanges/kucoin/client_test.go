@@ -1,33 +1,287 @@
 package kucoin
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"github.com/buger/jsonparser"
+	"io"
+	"main/exchanges"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
 
 var client *Client
 
-func TestCheckConnection(t *testing.T) {
+var btcusdc = exchanges.DefaultSymbol
+
+// fakeKuCoin is a minimal stand-in of the KuCoin spot REST API. Every request
+// must carry a valid v2 signature, otherwise it answers like KuCoin does.
+func fakeKuCoin() *httptest.Server {
+	orders := map[string]string{
+		"ord-filled": `{"id":"ord-filled","symbol":"BTC-USDC","side":"buy","price":"90000","size":"0.0001","dealSize":"0.0001","isActive":false,"cancelExist":false}`,
+		"ord-active": `{"id":"ord-active","symbol":"BTC-USDC","side":"sell","price":"95000","size":"0.0001","dealSize":"0","isActive":true,"cancelExist":false}`,
+	}
+
+	// Canceled orders read back inactive
+	canceled := map[string]bool{}
+
+	reply := func(w http.ResponseWriter, data string) {
+		_, _ = io.WriteString(w, `{"code":"200000","data":`+data+`}`)
+	}
 
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		timestamp := r.Header.Get("KC-API-TIMESTAMP")
+		expected := client.sign(timestamp + r.Method + r.URL.RequestURI() + string(body))
+		if r.Header.Get("KC-API-KEY") != client.APIKey ||
+			r.Header.Get("KC-API-SIGN") != expected ||
+			r.Header.Get("KC-API-PASSPHRASE") != client.sign(client.APIPassphrase) ||
+			r.Header.Get("KC-API-KEY-VERSION") != "2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"code":"400005","msg":"Invalid KC-API-SIGN"}`)
+			return
+		}
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v1/timestamp":
+			reply(w, strconv.Itoa(1700000000000))
+		case r.Method == "GET" && r.URL.Path == "/api/v1/accounts":
+			reply(w, `[{"id":"1","currency":"USDC","type":"trade","balance":"150.5","available":"120.25","holds":"30.25"}]`)
+		case r.Method == "GET" && r.URL.Path == "/api/v1/market/orderbook/level1":
+			if r.URL.Query().Get("symbol") != "BTC-USDC" {
+				reply(w, `null`)
+				return
+			}
+			reply(w, `{"sequence":"1","price":"91234.5","size":"0.01","bestBid":"91234.4","bestAsk":"91234.6"}`)
+		case r.Method == "GET" && r.URL.Path == "/api/v2/symbols/BTC-USDC":
+			reply(w, `{"symbol":"BTC-USDC","baseCurrency":"BTC","quoteCurrency":"USDC","baseMinSize":"0.00001","quoteMinSize":"0.1","baseIncrement":"0.00000001","quoteIncrement":"0.000001","priceIncrement":"0.1","minFunds":"0.1","enableTrading":true}`)
+		case r.Method == "GET" && r.URL.Path == "/api/v1/fills":
+			reply(w, `{"currentPage":1,"pageSize":500,"totalNum":1,"totalPage":1,"items":[`+
+				`{"symbol":"BTC-USDC","tradeId":"t-1","orderId":"`+r.URL.Query().Get("orderId")+`","side":"buy","price":"90000","size":"0.0001","funds":"9","fee":"0.009","feeCurrency":"USDC","createdAt":1760432460123}]}`)
+		case r.Method == "GET" && r.URL.Path == "/api/v1/market/candles":
+			if r.URL.Query().Get("type") != "1hour" || r.URL.Query().Get("symbol") != "BTC-USDC" {
+				_, _ = io.WriteString(w, `{"code":"400100","msg":"invalid type"}`)
+				return
+			}
+			reply(w, `[`+
+				`["1760432400","90500","91100","91200","90100","8.25","751575"],`+
+				`["1760428800","90000","90500","91000","89500","12.5","1131250"],`+
+				`["1760425200","89000","90000","90100","88900","10","895000"]]`)
+		case r.Method == "POST" && r.URL.Path == "/api/v1/orders":
+			side, _ := jsonparser.GetString(body, "side")
+			kind, _ := jsonparser.GetString(body, "type")
+			if side != "buy" || kind != "limit" {
+				_, _ = io.WriteString(w, `{"code":"400100","msg":"invalid order"}`)
+				return
+			}
+			reply(w, `{"orderId":"ord-new"}`)
+		case r.Method == "GET" && r.URL.Path == "/api/v1/orders":
+			reply(w, `{"currentPage":1,"pageSize":50,"totalNum":1,"totalPage":1,"items":[`+orders["ord-active"]+`]}`)
+		case r.Method == "GET" && r.URL.Path == "/api/v1/order/client-order/bs-1a2b-7-buy":
+			reply(w, orders["ord-filled"])
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/api/v1/order/client-order/"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"code":"400100","msg":"order not exist."}`)
+		case r.Method == "GET" && len(r.URL.Path) > len("/api/v1/orders/"):
+			order, ok := orders[r.URL.Path[len("/api/v1/orders/"):]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = io.WriteString(w, `{"code":"400100","msg":"order not exist."}`)
+				return
+			}
+			if canceled[r.URL.Path[len("/api/v1/orders/"):]] {
+				order = strings.Replace(order, `"isActive":true,"cancelExist":false`, `"isActive":false,"cancelExist":true`, 1)
+			}
+			reply(w, order)
+		case r.Method == "DELETE" && len(r.URL.Path) > len("/api/v1/orders/"):
+			canceled[r.URL.Path[len("/api/v1/orders/"):]] = true
+			reply(w, `{"cancelledOrderIds":["`+r.URL.Path[len("/api/v1/orders/"):]+`"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
 }
 
-func TestGetLastPriceBTC(t *testing.T) {
+func TestMain(m *testing.M) {
+	client = &Client{
+		APIKey:        "key",
+		APISecret:     "secret",
+		APIPassphrase: "passphrase",
+	}
 
+	server := fakeKuCoin()
+	client.SetBaseURL(server.URL)
+
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
 }
 
-func TestGetBalanceUSD(t *testing.T) {
+func TestCheckConnection(t *testing.T) {
+	err := client.CheckConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetLastPrice(t *testing.T) {
+	price, err := client.GetLastPrice(context.Background(), btcusdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != 91234.5 {
+		t.Errorf("expected price 91234.5, got %v", price)
+	}
+}
 
+func TestGetBalance(t *testing.T) {
+	balance, err := client.GetBalance(context.Background(), "USDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 120.25 {
+		t.Errorf("expected balance 120.25, got %v", balance)
+	}
 }
 
 func TestCreateOrder(t *testing.T) {
+	order, err := client.CreateOrder(context.Background(), btcusdc, "BUY", "90000", "0.0001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Id != "ord-new" || order.Status != exchanges.OrderNew || order.Price != 90000 {
+		t.Errorf("expected new order ord-new at 90000, got %+v", order)
+	}
 
+	_, err = client.CreateOrder(context.Background(), btcusdc, "SELL_ALL", "90000", "0.0001", "")
+	if err == nil {
+		t.Error("expected an error for an invalid side")
+	}
 }
 
 func TestGetOrderById(t *testing.T) {
+	order, err := client.GetOrderById(context.Background(), btcusdc, "ord-active")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Id != "ord-active" || order.Side != "SELL" || order.Status != exchanges.OrderNew {
+		t.Errorf("expected active SELL order ord-active, got %+v", order)
+	}
+
+	_, err = client.GetOrderById(context.Background(), btcusdc, "unknown")
+	if !errors.Is(err, exchanges.ErrOrderNotFound) {
+		t.Errorf("expected an order not found error, got %v", err)
+	}
+}
 
+func TestGetOrderByClientId(t *testing.T) {
+	order, err := client.GetOrderByClientId(context.Background(), btcusdc, "bs-1a2b-7-buy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Id != "ord-filled" {
+		t.Errorf("expected order ord-filled, got %+v", order)
+	}
+
+	_, err = client.GetOrderByClientId(context.Background(), btcusdc, "bs-1a2b-8-buy")
+	if !errors.Is(err, exchanges.ErrOrderNotFound) {
+		t.Errorf("expected an order not found error, got %v", err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	err := apiError(http.StatusNotFound, []byte(`{"code":"400100","msg":"order not exist."}`))
+	if !errors.Is(err, exchanges.ErrOrderNotFound) {
+		t.Errorf("expected an order not found error, got %v", err)
+	}
+
+	// A parameter naming a missing thing is not an unknown order
+	err = apiError(http.StatusBadRequest, []byte(`{"code":"400100","msg":"symbol not exist"}`))
+	if !errors.Is(err, exchanges.ErrInvalidParameter) {
+		t.Errorf("expected an invalid parameter error, got %v", err)
+	}
 }
 
 func TestIsFilled(t *testing.T) {
+	for id, expected := range map[string]bool{"ord-filled": true, "ord-active": false} {
+		order, err := client.GetOrderById(context.Background(), btcusdc, id)
+		if err != nil {
+			t.Fatal(err)
+		}
 
+		if order.IsFilled() != expected {
+			t.Errorf("%s: expected filled=%v, got %v", id, expected, order.IsFilled())
+		}
+	}
 }
 
 func TestCancelOrder(t *testing.T) {
+	order, err := client.CancelOrder(context.Background(), btcusdc, "ord-active")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Id != "ord-active" || order.Status != exchanges.OrderCanceled {
+		t.Errorf("expected ord-active to be canceled, got %+v", order)
+	}
+	if order.OrigQty != 0.0001 || order.Price != 95000 || order.Side != "SELL" {
+		t.Errorf("expected the canceled order in full, got %+v", order)
+	}
+}
+
+func TestGetOpenOrders(t *testing.T) {
+	orders, err := client.GetOpenOrders(context.Background(), btcusdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders) != 1 || orders[0].Id != "ord-active" {
+		t.Errorf("expected ord-active in open orders, got %+v", orders)
+	}
+}
+
+func TestGetSymbolFilters(t *testing.T) {
+	filters, err := client.GetSymbolFilters(context.Background(), btcusdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := exchanges.SymbolFilters{TickSize: 0.1, StepSize: 0.00000001, MinQty: 0.00001, MinNotional: 0.1}
+	if filters != expected {
+		t.Errorf("expected %+v, got %+v", expected, filters)
+	}
+}
+
+func TestGetOrderTrades(t *testing.T) {
+	trades, err := client.GetOrderTrades(context.Background(), btcusdc, "ord-filled")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := exchanges.Trade{Id: "t-1", OrderId: "ord-filled", Price: 90000, Qty: 0.0001, QuoteQty: 9, Fee: 0.009, FeeAsset: "USDC", Time: 1760432460123}
+	if len(trades) != 1 || trades[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, trades)
+	}
+}
 
+func TestGetKlines(t *testing.T) {
+	klines, err := client.GetKlines(context.Background(), btcusdc, time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(klines) != 2 {
+		t.Fatalf("expected the last 2 klines, got %d", len(klines))
+	}
+	expected := exchanges.Kline{OpenTime: 1760432400000, Open: 90500, High: 91200, Low: 90100, Close: 91100, Volume: 8.25}
+	if klines[0].OpenTime != 1760428800000 || klines[1] != expected {
+		t.Errorf("expected the klines oldest first ending with %+v, got %+v", expected, klines)
+	}
 }
@@ -0,0 +1,46 @@
+package kucoin
+
+import (
+	"github.com/buger/jsonparser"
+	"main/exchanges"
+	"net/http"
+)
+
+// Error codes of the KuCoin API by kind
+var errorKinds = map[string]error{
+	"200004": exchanges.ErrInsufficientBalance, // Balance insufficient
+	"400001": exchanges.ErrAuth,                // Missing KC-API headers
+	"400002": exchanges.ErrAuth,                // Invalid KC-API-TIMESTAMP
+	"400003": exchanges.ErrAuth,                // KC-API-KEY not exists
+	"400004": exchanges.ErrAuth,                // Invalid KC-API-PASSPHRASE
+	"400005": exchanges.ErrAuth,                // Invalid KC-API-SIGN
+	"400006": exchanges.ErrAuth,                // IP not in the whitelist
+	"400007": exchanges.ErrAuth,                // Access denied
+	"411100": exchanges.ErrAuth,                // User is frozen
+	"400100": exchanges.ErrInvalidParameter,    // Parameter error
+	"429000": exchanges.ErrRateLimited,         // Too many requests
+	"500000": exchanges.ErrNetwork,             // Internal server error
+}
+
+// apiError reads the {"code":..., "msg":...} body of a failed request
+func apiError(statusCode int, body []byte) *exchanges.APIError {
+	apiErr := &exchanges.APIError{Exchange: "KuCoin", StatusCode: statusCode, Message: string(body)}
+
+	code, err := jsonparser.GetString(body, "code")
+	if err == nil {
+		apiErr.Code = code
+		apiErr.Message, _ = jsonparser.GetString(body, "msg")
+	}
+
+	kind, ok := errorKinds[apiErr.Code]
+	switch {
+	// 400100 is also the answer for an unknown order, with a 404
+	case apiErr.Code == "400100" && statusCode == http.StatusNotFound:
+		kind = exchanges.ErrOrderNotFound
+	case !ok:
+		kind = exchanges.StatusKind(statusCode)
+	}
+	apiErr.Kind = kind
+
+	return apiErr
+}
@@ -1,34 +1,60 @@
 package mexc
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/buger/jsonparser"
 	"github.com/fatih/color"
 	"io"
-	"log"
+	"main/exchanges"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"time"
 )
 
 type Client struct {
-	APIKey    string
-	APISecret string
-	BaseURL   string
+	APIKey     string
+	APISecret  string
+	BaseURL    string
+	StreamURL  string   // WebSocket of the user data stream
+	RecvWindow int64    // milliseconds a signed request stays valid, 5000 when 0
+	limiter    *limiter // sharedLimiter when nil
+	clock      *clock   // sharedClock when nil
 }
 
 func NewClient() *Client {
 	return &Client{
-		APIKey:    os.Getenv("MEXC_API_KEY"),
-		APISecret: os.Getenv("MEXC_SECRET_KEY"),
-		BaseURL:   "https://api.mexc.com",
+		APIKey:     os.Getenv("MEXC_API_KEY"),
+		APISecret:  os.Getenv("MEXC_SECRET_KEY"),
+		BaseURL:    "https://api.mexc.com",
+		StreamURL:  "wss://wbs.mexc.com/ws",
+		RecvWindow: recvWindowFromEnv(),
 	}
 }
 
+// MEXC_RECV_WINDOW in milliseconds, at most 60000 like MEXC allows. An
+// invalid value falls back to the default.
+func recvWindowFromEnv() int64 {
+	str := os.Getenv("MEXC_RECV_WINDOW")
+	if str == "" {
+		return 0
+	}
+
+	recvWindow, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || recvWindow <= 0 || recvWindow > 60000 {
+		color.Red("MEXC_RECV_WINDOW must be a number of milliseconds between 1 and 60000, using 5000")
+		return 0
+	}
+	return recvWindow
+}
+
 func (c *Client) SetBaseURL(url string) {
 	c.BaseURL = url
 }
@@ -40,74 +66,131 @@ func (c *Client) signRequest(queryString string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-// Sends an HTTP request and returns the response body
-func (c *Client) sendRequest(method, endpoint, queryString string) ([]byte, error) {
+// Sends an HTTP request and returns the response body. Calls wait for the
+// rate limiter and are retried with exponential backoff when throttled,
+// within exchanges.CallTimeout.
+func (c *Client) sendRequest(ctx context.Context, method, endpoint, queryString string) ([]byte, error) {
 	fullURL := fmt.Sprintf("%s%s?%s", c.BaseURL, endpoint, queryString)
 
-	req, err := http.NewRequest(method, fullURL, nil)
-	if err != nil {
-		return nil, err
+	ctx, cancel := context.WithTimeout(ctx, exchanges.CallTimeout)
+	defer cancel()
+
+	limiter := c.limiter
+	if limiter == nil {
+		limiter = sharedLimiter
 	}
 
-	req.Header.Set("X-MEXC-APIKEY", c.APIKey)
+	for attempt := 0; ; attempt++ {
+		err := limiter.wait(ctx, endpointWeight(endpoint))
+		if err != nil {
+			return nil, exchanges.NetworkError("MEXC", err)
+		}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
+		req, err := http.NewRequestWithContext(ctx, method, fullURL, nil)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-	}(resp.Body)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		//fmt.Println("Raw API Response:", string(body))
-		return nil, err
+		req.Header.Set("X-MEXC-APIKEY", c.APIKey)
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			if attempt+1 < maxAttempts && retryable(method, nil, err) && sleep(ctx, backoff(attempt)) == nil {
+				continue
+			}
+			return nil, exchanges.NetworkError("MEXC", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return body, nil
+		}
+
+		if attempt+1 < maxAttempts && retryable(method, resp, nil) {
+			delay := backoff(attempt)
+			if after, ok := retryAfter(resp); ok {
+				delay = after
+				limiter.block(after)
+			}
+			color.Yellow("MEXC HTTP status %d, retrying in %s", resp.StatusCode, delay)
+			err := sleep(ctx, delay)
+			if err != nil {
+				return nil, exchanges.NetworkError("MEXC", err)
+			}
+			continue
+		}
+
+		return nil, apiError(resp.StatusCode, body)
 	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error: HTTP status %d - %s", resp.StatusCode, string(body))
+// Sends a signed request. The timestamp follows the server clock and a
+// request rejected for its timestamp is sent again after a new sync.
+func (c *Client) sendSignedRequest(ctx context.Context, method, endpoint, queryString string) ([]byte, error) {
+	recvWindow := c.RecvWindow
+	if recvWindow == 0 {
+		recvWindow = 5000
 	}
 
-	return body, nil
+	for attempt := 0; ; attempt++ {
+		timestamp, err := c.now(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		query := fmt.Sprintf("recvWindow=%d&timestamp=%d", recvWindow, timestamp)
+		if queryString != "" {
+			query = queryString + "&" + query
+		}
+		signedQuery := fmt.Sprintf("%s&signature=%s", query, c.signRequest(query))
+
+		body, err := c.sendRequest(ctx, method, endpoint, signedQuery)
+		var apiErr *exchanges.APIError
+		if attempt == 0 && errors.As(err, &apiErr) && apiErr.Code == codeTimestampOutside {
+			color.Yellow("MEXC rejected the request timestamp, syncing the clock")
+			err = c.syncTime(ctx)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return body, err
+	}
 }
 
-func (c *Client) CheckConnection() {
-	_, err := c.sendRequest("GET", "/api/v3/ping", "")
+func (c *Client) CheckConnection(ctx context.Context) error {
+	_, err := c.sendRequest(ctx, "GET", "/api/v3/ping", "")
 	if err != nil {
-		log.Fatalf("Failed to connect to MEXC: %v", err)
+		return fmt.Errorf("failed to connect to MEXC: %w", err)
 	}
 
 	color.Green("Connected to MEXC API successfully")
 	fmt.Println("")
+	return nil
 }
 
-func (c *Client) GetBalanceUSD() (float64, error) {
-	color.Blue("Checking USDC balance...")
-
-	timestamp := time.Now().UnixMilli()
-	queryString := fmt.Sprintf("timestamp=%d", timestamp)
-	signature := c.signRequest(queryString)
-	signedQuery := fmt.Sprintf("%s&signature=%s", queryString, signature)
+func (c *Client) GetBalance(ctx context.Context, asset string) (float64, error) {
+	color.Blue("Checking %s balance...", asset)
 
-	body, err := c.sendRequest("GET", "/api/v3/account", signedQuery)
+	body, err := c.sendSignedRequest(ctx, "GET", "/api/v3/account", "")
 	if err != nil {
-		log.Fatalf("Error fetching balance: %v", err)
+		return 0, fmt.Errorf("error fetching balance: %w", err)
 	}
 
 	balances, _, _, err := jsonparser.Get(body, "balances")
 	if err != nil {
-		log.Fatalf("Error getting balances: %v", err)
+		return 0, fmt.Errorf("error getting balances: %v", err)
 	}
 
 	var freeFloat float64
 	_, err = jsonparser.ArrayEach(balances, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
-		asset, _ := jsonparser.GetString(value, "asset")
-		if asset == "USDC" {
+		balanceAsset, _ := jsonparser.GetString(value, "asset")
+		if balanceAsset == asset {
 			freeStr, _ := jsonparser.GetString(value, "free")
 			free, _ := strconv.ParseFloat(freeStr, 64)
 			freeFloat = free
@@ -117,100 +200,171 @@ func (c *Client) GetBalanceUSD() (float64, error) {
 	return freeFloat, nil
 }
 
-func (c *Client) GetLastPriceBTC() (float64, error) {
-	queryString := "symbol=BTCUSDC"
-	body, err := c.sendRequest("GET", "/api/v3/ticker/price", queryString)
+func (c *Client) GetLastPrice(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	queryString := "symbol=" + symbol.Join("")
+	body, err := c.sendRequest(ctx, "GET", "/api/v3/ticker/price", queryString)
 	if err != nil {
-		log.Fatalf("Error fetching BTC price: %v", err)
+		return 0, fmt.Errorf("error fetching %s price: %w", symbol, err)
 	}
 
 	priceStr, err := jsonparser.GetString(body, "price")
 	if err != nil {
-		log.Fatalf("Error extracting price: %v", err)
+		return 0, fmt.Errorf("error extracting price: %v", err)
 	}
 
 	price, err := strconv.ParseFloat(priceStr, 64)
 	if err != nil {
-		log.Fatalf("Error converting price: %v", err)
+		return 0, fmt.Errorf("error converting price: %v", err)
 	}
 
 	return price, nil
 }
 
-func (c *Client) CreateOrder(side string, price, quantity string) ([]byte, error) {
-	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
-
+// CreateOrder places a limit order. clientId, when set, is sent as
+// newClientOrderId so the order can be found without its id.
+func (c *Client) CreateOrder(ctx context.Context, symbol exchanges.Symbol, side string, price, quantity, clientId string) (*exchanges.Order, error) {
 	queryString := fmt.Sprintf(
-		"symbol=BTCUSDC&side=%s&type=LIMIT&quantity=%s&price=%s&timestamp=%s",
-		side, quantity, price, timestamp,
+		"symbol=%s&side=%s&type=LIMIT&quantity=%s&price=%s",
+		symbol.Join(""), side, quantity, price,
 	)
+	if clientId != "" {
+		queryString += "&newClientOrderId=" + url.QueryEscape(clientId)
+	}
 
-	signature := c.signRequest(queryString)
-	signedQuery := fmt.Sprintf("%s&signature=%s", queryString, signature)
+	body, err := c.sendSignedRequest(ctx, "POST", "/api/v3/order", queryString)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	return exchanges.ParseBinanceOrder(symbol, body, orderStatus)
+}
+
+func (c *Client) GetOrderById(ctx context.Context, symbol exchanges.Symbol, id string) (*exchanges.Order, error) {
+	queryString := fmt.Sprintf("symbol=%s&orderId=%s", symbol.Join(""), id)
 
-	// Send request
-	body, err := c.sendRequest("POST", "/api/v3/order", signedQuery)
+	body, err := c.sendSignedRequest(ctx, "GET", "/api/v3/order", queryString)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request: %v", err)
+		return nil, fmt.Errorf("error sending request: %w", err)
 	}
 
-	return body, nil
+	return exchanges.ParseBinanceOrder(symbol, body, orderStatus)
 }
 
-func (c *Client) GetOrderById(id string) ([]byte, error) {
-	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+func (c *Client) GetOrderByClientId(ctx context.Context, symbol exchanges.Symbol, clientId string) (*exchanges.Order, error) {
+	queryString := fmt.Sprintf("symbol=%s&origClientOrderId=%s", symbol.Join(""), url.QueryEscape(clientId))
+
+	body, err := c.sendSignedRequest(ctx, "GET", "/api/v3/order", queryString)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	return exchanges.ParseBinanceOrder(symbol, body, orderStatus)
+}
 
-	queryString := fmt.Sprintf("symbol=BTCUSDC&orderId=%s&timestamp=%s", id, timestamp)
-	signature := c.signRequest(queryString)
-	signedQuery := fmt.Sprintf("%s&signature=%s", queryString, signature)
+func (c *Client) CancelOrder(ctx context.Context, symbol exchanges.Symbol, orderID string) (*exchanges.Order, error) {
+	queryString := fmt.Sprintf("symbol=%s&orderId=%s", symbol.Join(""), orderID)
 
-	// Send request
-	body, err := c.sendRequest("GET", "/api/v3/order", signedQuery)
+	body, err := c.sendSignedRequest(ctx, "DELETE", "/api/v3/order", queryString)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request: %v", err)
+		return nil, fmt.Errorf("error canceling order %s: %w", orderID, err)
 	}
 
-	//fmt.Println("Raw API Response:", string(body))
+	color.Green("Order %s canceled successfully", orderID)
+	return exchanges.ParseBinanceOrder(symbol, body, orderStatus)
+}
 
-	return body, nil
+func (c *Client) GetOpenOrders(ctx context.Context, symbol exchanges.Symbol) ([]exchanges.Order, error) {
+	queryString := fmt.Sprintf("symbol=%s", symbol.Join(""))
+
+	body, err := c.sendSignedRequest(ctx, "GET", "/api/v3/openOrders", queryString)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching open orders: %w", err)
+	}
+
+	return exchanges.ParseBinanceOrders(symbol, body, orderStatus)
 }
 
-func (c *Client) IsFilled(order string) (bool, error) {
-	status, err := jsonparser.GetString([]byte(order), "status")
+// GetOrderTrades returns the fills of an order with their commission
+func (c *Client) GetOrderTrades(ctx context.Context, symbol exchanges.Symbol, orderID string) ([]exchanges.Trade, error) {
+	queryString := fmt.Sprintf("symbol=%s&orderId=%s", symbol.Join(""), orderID)
+
+	body, err := c.sendSignedRequest(ctx, "GET", "/api/v3/myTrades", queryString)
 	if err != nil {
-		return false, fmt.Errorf("failed to parse order status: %w", err)
+		return nil, fmt.Errorf("error fetching trades of order %s: %w", orderID, err)
 	}
 
-	return status == "FILLED", nil
+	return exchanges.ParseBinanceTrades(orderID, body)
 }
 
-func (c *Client) CancelOrder(orderID string) ([]byte, error) {
-	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+// GetSymbolFilters reads the precision rules of symbol from exchangeInfo.
+// MEXC gives them as precisions rather than Binance-like filters.
+func (c *Client) GetSymbolFilters(ctx context.Context, symbol exchanges.Symbol) (exchanges.SymbolFilters, error) {
+	var filters exchanges.SymbolFilters
 
-	queryString := fmt.Sprintf("symbol=BTCUSDC&orderId=%s&timestamp=%s", orderID, timestamp)
-	signature := c.signRequest(queryString)
-	signedQuery := fmt.Sprintf("%s&signature=%s", queryString, signature)
+	body, err := c.sendRequest(ctx, "GET", "/api/v3/exchangeInfo", "symbol="+symbol.Join(""))
+	if err != nil {
+		return filters, fmt.Errorf("error fetching exchange info: %w", err)
+	}
 
-	body, err := c.sendRequest("DELETE", "/api/v3/order", signedQuery)
+	info, _, _, err := jsonparser.Get(body, "symbols", "[0]")
 	if err != nil {
-		return nil, fmt.Errorf("error canceling order %s: %v", orderID, err)
+		return filters, fmt.Errorf("symbol %s not found in exchange info", symbol)
 	}
 
-	color.Green("Order %s canceled successfully", orderID)
-	return body, nil
+	quotePrecision, err := jsonparser.GetInt(info, "quotePrecision")
+	if err != nil {
+		return filters, fmt.Errorf("error extracting quotePrecision: %v", err)
+	}
+	baseAssetPrecision, err := jsonparser.GetInt(info, "baseAssetPrecision")
+	if err != nil {
+		return filters, fmt.Errorf("error extracting baseAssetPrecision: %v", err)
+	}
+	filters.TickSize = math.Pow10(-int(quotePrecision))
+	filters.StepSize = math.Pow10(-int(baseAssetPrecision))
+
+	if str, err := jsonparser.GetString(info, "baseSizePrecision"); err == nil {
+		filters.MinQty, _ = strconv.ParseFloat(str, 64)
+	}
+	if str, err := jsonparser.GetString(info, "quoteAmountPrecision"); err == nil {
+		filters.MinNotional, _ = strconv.ParseFloat(str, 64)
+	}
+
+	return filters, nil
 }
 
-func (c *Client) GetOpenOrders() ([]byte, error) {
-	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+// Kline intervals of MEXC
+var klineIntervals = map[time.Duration]string{
+	time.Minute:        "1m",
+	5 * time.Minute:    "5m",
+	15 * time.Minute:   "15m",
+	30 * time.Minute:   "30m",
+	time.Hour:          "60m",
+	4 * time.Hour:      "4h",
+	24 * time.Hour:     "1d",
+	7 * 24 * time.Hour: "1W",
+}
 
-	queryString := fmt.Sprintf("symbol=BTCUSDC&timestamp=%s", timestamp)
-	signature := c.signRequest(queryString)
-	signedQuery := fmt.Sprintf("%s&signature=%s", queryString, signature)
+// GetKlines returns the last limit klines of symbol, oldest first. The last
+// one is still open.
+func (c *Client) GetKlines(ctx context.Context, symbol exchanges.Symbol, interval time.Duration, limit int) ([]exchanges.Kline, error) {
+	name, err := exchanges.KlineInterval("MEXC", interval, klineIntervals)
+	if err != nil {
+		return nil, err
+	}
 
-	body, err := c.sendRequest("GET", "/api/v3/openOrders", signedQuery)
+	queryString := fmt.Sprintf("symbol=%s&interval=%s&limit=%d", symbol.Join(""), name, limit)
+	body, err := c.sendRequest(ctx, "GET", "/api/v3/klines", queryString)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching open orders: %v", err)
+		return nil, fmt.Errorf("error fetching %s klines: %w", symbol, err)
 	}
 
-	return body, nil
+	return exchanges.ParseBinanceKlines(body)
+}
+
+func orderStatus(status string) exchanges.OrderStatus {
+	switch status {
+	case "PARTIALLY_CANCELED": // canceled after a partial fill
+		return exchanges.OrderCanceled
+	}
+	return exchanges.OrderStatus(status)
 }
@@ -1,15 +1,20 @@
 package mexc
 
 import (
+	"context"
 	"fmt"
-	"github.com/buger/jsonparser"
 	"github.com/joho/godotenv"
+	"main/exchanges"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 )
 
 var client *Client
 
+var btcusdc = exchanges.DefaultSymbol
+
 func TestMain(m *testing.M) {
 	// TODO make config folder
 	_ = godotenv.Load("../../bot.conf")
@@ -21,28 +26,51 @@ func TestMain(m *testing.M) {
 }
 
 func TestCheckConnection(t *testing.T) {
-	client.CheckConnection()
+	err := client.CheckConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
 }
 
-func TestClient_GetBalanceUSD(t *testing.T) {
-	balance, _ := client.GetBalanceUSD()
+func TestClient_GetBalance(t *testing.T) {
+	balance, _ := client.GetBalance(context.Background(), "USDC")
 	fmt.Println("balance:", balance)
 }
 
-func TestClient_GetOrderById(t *testing.T) {
-	orderId := os.Getenv("ORDER_ID")
-	order, err := client.GetOrderById(orderId)
+func TestClient_GetSymbolFilters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/exchangeInfo" || r.URL.Query().Get("symbol") != "BTCUSDC" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"timezone":"CST","serverTime":1760432400000,"symbols":[{"symbol":"BTCUSDC","status":"1","baseAsset":"BTC","baseAssetPrecision":6,"quoteAsset":"USDC","quotePrecision":2,"quoteAssetPrecision":2,"baseSizePrecision":"0.000001","quoteAmountPrecision":"1.000000","isSpotTradingAllowed":true}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient()
+	c.SetBaseURL(server.URL)
 
+	filters, err := c.GetSymbolFilters(context.Background(), btcusdc)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	orderJSON, err := jsonparser.ParseString(order)
+	expected := exchanges.SymbolFilters{TickSize: 0.01, StepSize: 0.000001, MinQty: 0.000001, MinNotional: 1}
+	if filters != expected {
+		t.Errorf("expected %+v, got %+v", expected, filters)
+	}
+}
 
-	fmt.Println(orderJSON)
+func TestClient_GetOrderById(t *testing.T) {
+	orderId := os.Getenv("ORDER_ID")
+	order, err := client.GetOrderById(context.Background(), btcusdc, orderId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	isFilled, _ := client.IsFilled(orderJSON)
-	fmt.Println("is filled:", isFilled)
+	fmt.Printf("%+v\n", order)
+	fmt.Println("is filled:", order.IsFilled())
 }
 
 func TestCreateOrder(t *testing.T) {
@@ -50,10 +78,10 @@ func TestCreateOrder(t *testing.T) {
 	price := "86600"
 	quantity := "0.000025"
 
-	order, err := client.CreateOrder(side, price, quantity)
+	order, err := client.CreateOrder(context.Background(), btcusdc, side, price, quantity, "")
 	if err != nil {
 		t.Error(err)
 	}
 
-	fmt.Println(string(order))
+	fmt.Printf("%+v\n", order)
 }
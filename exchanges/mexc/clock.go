@@ -0,0 +1,78 @@
+package mexc
+
+import (
+	"context"
+	"fmt"
+	"github.com/buger/jsonparser"
+	"sync"
+	"time"
+)
+
+// How often the offset with the server clock is measured again
+var timeSyncInterval = 10 * time.Minute
+
+// clock keeps the offset between the MEXC server time and the local time,
+// so signed requests carry a timestamp the server accepts even when the
+// local clock drifts
+type clock struct {
+	mu       sync.Mutex
+	offset   time.Duration
+	syncedAt time.Time
+}
+
+// Shared by every client like sharedLimiter, the offset is the same for all
+var sharedClock = &clock{}
+
+// now returns the server time in milliseconds, measuring the offset first
+// when it is older than timeSyncInterval
+func (c *Client) now(ctx context.Context) (int64, error) {
+	clk := c.clock
+	if clk == nil {
+		clk = sharedClock
+	}
+
+	clk.mu.Lock()
+	stale := clk.syncedAt.IsZero() || time.Since(clk.syncedAt) > timeSyncInterval
+	clk.mu.Unlock()
+
+	if stale {
+		err := c.syncTime(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	clk.mu.Lock()
+	defer clk.mu.Unlock()
+	return time.Now().Add(clk.offset).UnixMilli(), nil
+}
+
+// syncTime measures the offset with /api/v3/time. The server time is
+// compared to the middle of the round trip.
+func (c *Client) syncTime(ctx context.Context) error {
+	clk := c.clock
+	if clk == nil {
+		clk = sharedClock
+	}
+
+	start := time.Now()
+	body, err := c.sendRequest(ctx, "GET", "/api/v3/time", "")
+	if err != nil {
+		return fmt.Errorf("error fetching server time: %w", err)
+	}
+	end := time.Now()
+
+	serverTime, err := jsonparser.GetInt(body, "serverTime")
+	if err != nil {
+		return fmt.Errorf("error extracting serverTime: %v", err)
+	}
+
+	local := start.Add(end.Sub(start) / 2)
+
+	clk.mu.Lock()
+	defer clk.mu.Unlock()
+	clk.offset = time.UnixMilli(serverTime).Sub(local)
+	clk.syncedAt = end
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package mexc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// skewedServer runs its clock skew ahead of the local one and rejects signed
+// requests outside of their recvWindow like MEXC does
+func skewedServer(t *testing.T, skew time.Duration) (*Client, *atomic.Int32) {
+	var rejected atomic.Int32
+	client := &Client{APISecret: "secret", limiter: newLimiter(500, 10*time.Second), clock: &clock{}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverTime := time.Now().Add(skew).UnixMilli()
+
+		if r.URL.Path == "/api/v3/time" {
+			_, _ = fmt.Fprintf(w, `{"serverTime":%d}`, serverTime)
+			return
+		}
+
+		query := r.URL.Query()
+		raw := r.URL.RawQuery
+		signed := raw[:len(raw)-len("&signature=")-len(query.Get("signature"))]
+		if client.signRequest(signed) != query.Get("signature") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"code":700002,"msg":"Signature for this request is not valid."}`)
+			return
+		}
+
+		timestamp, _ := strconv.ParseInt(query.Get("timestamp"), 10, 64)
+		recvWindow, _ := strconv.ParseInt(query.Get("recvWindow"), 10, 64)
+		if timestamp > serverTime+1000 || serverTime-timestamp > recvWindow {
+			rejected.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"code":700003,"msg":"Timestamp for this request is outside of the recvWindow."}`)
+			return
+		}
+
+		_, _ = io.WriteString(w, `{"balances":[{"asset":"USDC","free":"12.5","locked":"0"}]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client.BaseURL = server.URL
+	return client, &rejected
+}
+
+func TestClock_SkewedServer(t *testing.T) {
+	for _, skew := range []time.Duration{-30 * time.Second, 20 * time.Second} {
+		t.Run(skew.String(), func(t *testing.T) {
+			client, rejected := skewedServer(t, skew)
+
+			balance, err := client.GetBalance(context.Background(), "USDC")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if balance != 12.5 {
+				t.Errorf("expected a balance of 12.5, got %f", balance)
+			}
+			if rejected.Load() != 0 {
+				t.Errorf("expected no rejected request, got %d", rejected.Load())
+			}
+
+			offset := client.clock.offset
+			if offset < skew-time.Second || offset > skew+time.Second {
+				t.Errorf("expected an offset near %s, got %s", skew, offset)
+			}
+		})
+	}
+}
+
+func TestClock_ResyncOnRejectedTimestamp(t *testing.T) {
+	client, rejected := skewedServer(t, 20*time.Second)
+
+	// Offset measured before the server clock drifted
+	client.clock.syncedAt = time.Now()
+
+	_, err := client.GetBalance(context.Background(), "USDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Load() != 1 {
+		t.Errorf("expected 1 rejected request, got %d", rejected.Load())
+	}
+	if client.clock.offset < 19*time.Second {
+		t.Errorf("expected the clock to be synced again, offset %s", client.clock.offset)
+	}
+}
+
+func TestClock_RecvWindow(t *testing.T) {
+	client, rejected := skewedServer(t, 8*time.Second)
+	client.RecvWindow = 10000
+
+	// Not synced, the timestamp is 8s late but still inside the window
+	client.clock.syncedAt = time.Now()
+	_, err := client.GetBalance(context.Background(), "USDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Load() != 0 {
+		t.Errorf("expected no rejected request, got %d", rejected.Load())
+	}
+
+	t.Setenv("MEXC_RECV_WINDOW", "10000")
+	if recvWindowFromEnv() != 10000 {
+		t.Errorf("expected MEXC_RECV_WINDOW to be read")
+	}
+}
@@ -0,0 +1,48 @@
+package mexc
+
+import (
+	"github.com/buger/jsonparser"
+	"main/exchanges"
+)
+
+// Error codes of the MEXC API by kind
+var errorKinds = map[string]error{
+	"10101":  exchanges.ErrInsufficientBalance, // Insufficient balance
+	"30004":  exchanges.ErrInsufficientBalance, // Insufficient position
+	"30005":  exchanges.ErrInsufficientBalance, // Oversold
+	"-2011":  exchanges.ErrOrderNotFound,       // Unknown order sent
+	"-2013":  exchanges.ErrOrderNotFound,       // Order does not exist
+	"10072":  exchanges.ErrAuth,                // Invalid access key
+	"700001": exchanges.ErrAuth,                // API-key format invalid
+	"700002": exchanges.ErrAuth,                // Signature for this request is not valid
+	"700006": exchanges.ErrAuth,                // IP not in the whitelist
+	"700007": exchanges.ErrAuth,                // No permission to access the endpoint
+	"700004": exchanges.ErrInvalidParameter,    // Mandatory parameter missing
+	"700005": exchanges.ErrInvalidParameter,    // recvWindow must be less than 60000
+	"33333":  exchanges.ErrInvalidParameter,    // Parameter error
+	"30002":  exchanges.ErrInvalidParameter,    // Minimum transaction volume not reached
+	"30029":  exchanges.ErrInvalidParameter,    // Maximum order quantity exceeded
+	"-1121":  exchanges.ErrInvalidParameter,    // Invalid symbol
+}
+
+// Code MEXC answers when the timestamp is outside of the recvWindow
+const codeTimestampOutside = "700003"
+
+// apiError reads the {"code":..., "msg":...} body of a failed request
+func apiError(statusCode int, body []byte) *exchanges.APIError {
+	apiErr := &exchanges.APIError{Exchange: "MEXC", StatusCode: statusCode, Message: string(body)}
+
+	code, _, _, err := jsonparser.Get(body, "code")
+	if err == nil {
+		apiErr.Code = string(code)
+		apiErr.Message, _ = jsonparser.GetString(body, "msg")
+	}
+
+	kind, ok := errorKinds[apiErr.Code]
+	if !ok {
+		kind = exchanges.StatusKind(statusCode)
+	}
+	apiErr.Kind = kind
+
+	return apiErr
+}
@@ -0,0 +1,240 @@
+package mexc
+
+import (
+	"context"
+	"fmt"
+	"github.com/buger/jsonparser"
+	"github.com/fatih/color"
+	"github.com/gorilla/websocket"
+	"main/exchanges"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Channels of the order updates of the account and of the trades of a
+// symbol, followed by the symbol
+const (
+	ordersChannel = "spot@private.orders.v3.api"
+	dealsChannel  = "spot@public.deals.v3.api@"
+)
+
+// A listen key expires after 60 minutes without keepalive. MEXC closes a
+// connection without message for 60 seconds.
+var (
+	keepAliveInterval = 30 * time.Minute
+	pingInterval      = 20 * time.Second
+)
+
+// Order statuses of the stream, by their number
+var streamStatuses = map[int64]exchanges.OrderStatus{
+	1: exchanges.OrderNew,
+	2: exchanges.OrderFilled,
+	3: exchanges.OrderPartiallyFilled,
+	4: exchanges.OrderCanceled,
+	5: exchanges.OrderCanceled, // canceled after a partial fill
+}
+
+func (c *Client) createListenKey(ctx context.Context) (string, error) {
+	body, err := c.sendSignedRequest(ctx, "POST", "/api/v3/userDataStream", "")
+	if err != nil {
+		return "", fmt.Errorf("error creating listen key: %w", err)
+	}
+
+	key, err := jsonparser.GetString(body, "listenKey")
+	if err != nil {
+		return "", fmt.Errorf("error extracting listenKey: %v", err)
+	}
+	return key, nil
+}
+
+func (c *Client) keepAliveListenKey(ctx context.Context, key string) error {
+	_, err := c.sendSignedRequest(ctx, "PUT", "/api/v3/userDataStream", "listenKey="+url.QueryEscape(key))
+	if err != nil {
+		return fmt.Errorf("error extending listen key: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) deleteListenKey(ctx context.Context, key string) error {
+	_, err := c.sendSignedRequest(ctx, "DELETE", "/api/v3/userDataStream", "listenKey="+url.QueryEscape(key))
+	if err != nil {
+		return fmt.Errorf("error deleting listen key: %w", err)
+	}
+	return nil
+}
+
+// StreamOrders sends the updates of the account orders to events. It blocks
+// until ctx is done, then returns nil, or until the connection drops.
+func (c *Client) StreamOrders(ctx context.Context, events chan<- exchanges.OrderEvent) error {
+	key, err := c.createListenKey(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Deleted even when the stream stops on shutdown
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), exchanges.CallTimeout)
+		defer cancel()
+		_ = c.deleteListenKey(ctx, key)
+	}()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	return c.subscribe(ctx, "?listenKey="+url.QueryEscape(key), ordersChannel, func(message []byte) {
+		event, err := parseOrderEvent(message)
+		if err != nil {
+			color.Yellow("Ignoring MEXC order event: %v", err)
+			return
+		}
+		select {
+		case events <- *event:
+		case <-ctx.Done():
+		}
+	}, keepAlive.C, func() error {
+		return c.keepAliveListenKey(ctx, key)
+	})
+}
+
+// StreamPrices sends the price of each trade of symbol to prices. It blocks
+// until ctx is done, then returns nil, or until the connection drops.
+func (c *Client) StreamPrices(ctx context.Context, symbol exchanges.Symbol, prices chan<- float64) error {
+	return c.subscribe(ctx, "", dealsChannel+symbol.Join(""), func(message []byte) {
+		price, err := parseDealsPrice(message)
+		if err != nil {
+			color.Yellow("Ignoring MEXC deals: %v", err)
+			return
+		}
+		select {
+		case prices <- price:
+		case <-ctx.Done():
+		}
+	}, nil, nil)
+}
+
+// subscribe dials the stream, subscribes to channel and hands each message
+// of the channel to handle. keepAlive is called on each tick of tick, when
+// set.
+func (c *Client) subscribe(ctx context.Context, query, channel string, handle func(message []byte), tick <-chan time.Time, keepAlive func() error) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, c.StreamURL+query, nil)
+	if err != nil {
+		return exchanges.NetworkError("MEXC", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	err = conn.WriteJSON(map[string]interface{}{"method": "SUBSCRIPTION", "params": []string{channel}})
+	if err != nil {
+		return exchanges.NetworkError("MEXC", err)
+	}
+
+	// Only this goroutine writes, the reader hands what it gets
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- readChannel(conn, channel, handle)
+	}()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return nil
+		case err := <-readErr:
+			return err
+		case <-ping.C:
+			err := conn.WriteJSON(map[string]string{"method": "PING"})
+			if err != nil {
+				return exchanges.NetworkError("MEXC", err)
+			}
+		case <-tick:
+			err := keepAlive()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readChannel reads the connection until it fails. Answers to SUBSCRIPTION
+// and PING carry a code, updates a channel.
+func readChannel(conn *websocket.Conn, channel string, handle func(message []byte)) error {
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return exchanges.NetworkError("MEXC", err)
+		}
+
+		if code, err := jsonparser.GetInt(message, "code"); err == nil {
+			if code != 0 {
+				msg, _ := jsonparser.GetString(message, "msg")
+				return fmt.Errorf("MEXC stream error %d: %s", code, msg)
+			}
+			continue
+		}
+
+		if c, _ := jsonparser.GetString(message, "c"); c == channel {
+			handle(message)
+		}
+	}
+}
+
+// Parses the trades of a symbol, like
+// {"c":"spot@public.deals.v3.api@BTCUSDC","d":{"deals":[{"S":1,"p":"20233.84","t":1678174778493,"v":"0.001028"}],"e":"spot@public.deals.v3.api"},"s":"BTCUSDC","t":1678174778501}
+// The last trade gives the price.
+func parseDealsPrice(message []byte) (float64, error) {
+	var priceStr string
+	_, err := jsonparser.ArrayEach(message, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if p, err := jsonparser.GetString(value, "p"); err == nil {
+			priceStr = p
+		}
+	}, "d", "deals")
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse deals: %w", err)
+	}
+	if priceStr == "" {
+		return 0, fmt.Errorf("no deal price")
+	}
+
+	price, err := strconv.ParseFloat(priceStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error converting price: %v", err)
+	}
+	return price, nil
+}
+
+// Parses an order update, like
+// {"c":"spot@private.orders.v3.api","d":{"i":"...","c":"...","S":1,"s":2,"cv":"0.0001",...},"s":"BTCUSDC","t":1661938138193}
+func parseOrderEvent(message []byte) (*exchanges.OrderEvent, error) {
+	orderId, err := jsonparser.GetString(message, "d", "i")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse order event id: %w", err)
+	}
+
+	code, err := jsonparser.GetInt(message, "d", "s")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse order event status: %w", err)
+	}
+	status, ok := streamStatuses[code]
+	if !ok {
+		return nil, fmt.Errorf("unknown order event status %d", code)
+	}
+
+	event := &exchanges.OrderEvent{OrderId: orderId, Side: "BUY", Status: status}
+	event.ClientId, _ = jsonparser.GetString(message, "d", "c")
+	event.Pair, _ = jsonparser.GetString(message, "s")
+	event.Time, _ = jsonparser.GetInt(message, "t")
+	if side, _ := jsonparser.GetInt(message, "d", "S"); side == 2 {
+		event.Side = "SELL"
+	}
+
+	// Numbers or strings depending on the field
+	if value, _, _, err := jsonparser.Get(message, "d", "cv"); err == nil {
+		event.ExecutedQty, _ = strconv.ParseFloat(string(value), 64)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,159 @@
+package mexc
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"io"
+	"main/exchanges"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// streamServer stands in for the MEXC listen key endpoints and the stream.
+// Once subscribed, the connection is handed to serve.
+func streamServer(t *testing.T, serve func(conn *websocket.Conn)) (*Client, *atomic.Int32) {
+	var deleted atomic.Int32
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/userDataStream":
+			if r.Method == "DELETE" {
+				deleted.Add(1)
+			}
+			_, _ = io.WriteString(w, `{"listenKey":"key"}`)
+		case "/ws":
+			// Public channels need no listen key
+			if key := r.URL.Query().Get("listenKey"); key != "" && key != "key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer func() {
+				_ = conn.Close()
+			}()
+
+			_, message, err := conn.ReadMessage()
+			if err != nil || !strings.Contains(string(message), `"SUBSCRIPTION"`) {
+				t.Errorf("expected a subscription, got %s %v", message, err)
+				return
+			}
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"id":0,"code":0,"msg":"`+ordersChannel+`"}`))
+			serve(conn)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := &Client{
+		APISecret: "secret",
+		BaseURL:   server.URL,
+		StreamURL: "ws" + strings.TrimPrefix(server.URL, "http") + "/ws",
+		limiter:   newLimiter(500, 10*time.Second),
+		clock:     &clock{syncedAt: time.Now()},
+	}
+	return client, &deleted
+}
+
+func TestStreamOrders_Fill(t *testing.T) {
+	client, deleted := streamServer(t, func(conn *websocket.Conn) {
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"c":"spot@public.deals.v3.api@BTCUSDC","d":{},"s":"BTCUSDC"}`))
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"c":"`+ordersChannel+`","d":{"i":"C02__1","c":"bs-1a2b-7-buy","S":1,"s":2,"cv":"0.001"},"s":"BTCUSDC","t":1661938138193}`))
+	})
+
+	events := make(chan exchanges.OrderEvent, 1)
+	err := client.StreamOrders(context.Background(), events)
+	if !errors.Is(err, exchanges.ErrNetwork) {
+		t.Errorf("expected a network error once the stream drops, got %v", err)
+	}
+
+	select {
+	case event := <-events:
+		expected := exchanges.OrderEvent{OrderId: "C02__1", ClientId: "bs-1a2b-7-buy", Pair: "BTCUSDC", Side: "BUY", Status: exchanges.OrderFilled, ExecutedQty: 0.001, Time: 1661938138193}
+		if event != expected {
+			t.Errorf("expected %+v, got %+v", expected, event)
+		}
+	default:
+		t.Error("expected an order event")
+	}
+
+	if deleted.Load() != 1 {
+		t.Errorf("expected the listen key deleted once, got %d", deleted.Load())
+	}
+}
+
+func TestStreamOrders_Done(t *testing.T) {
+	subscribed := make(chan struct{})
+	client, _ := streamServer(t, func(conn *websocket.Conn) {
+		close(subscribed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- client.StreamOrders(ctx, make(chan exchanges.OrderEvent))
+	}()
+
+	<-subscribed
+	cancel()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("expected no error once done, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StreamOrders did not return once done")
+	}
+}
+
+func TestStreamPrices(t *testing.T) {
+	client, _ := streamServer(t, func(conn *websocket.Conn) {
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"c":"`+dealsChannel+`BTCUSDC","d":{"deals":[{"S":1,"p":"20233.84","t":1,"v":"0.001"},{"S":2,"p":"20234.5","t":2,"v":"0.002"}],"e":"spot@public.deals.v3.api"},"s":"BTCUSDC","t":2}`))
+	})
+
+	prices := make(chan float64, 1)
+	err := client.StreamPrices(context.Background(), exchanges.DefaultSymbol, prices)
+	if !errors.Is(err, exchanges.ErrNetwork) {
+		t.Errorf("expected a network error once the stream drops, got %v", err)
+	}
+
+	select {
+	case price := <-prices:
+		if price != 20234.5 {
+			t.Errorf("expected the price of the last deal 20234.5, got %v", price)
+		}
+	default:
+		t.Error("expected a price")
+	}
+}
+
+func TestParseOrderEvent(t *testing.T) {
+	event, err := parseOrderEvent([]byte(`{"c":"` + ordersChannel + `","d":{"i":"C02__2","S":2,"s":5,"cv":"0.0004"},"s":"BTCUSDC","t":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Side != "SELL" || event.Status != exchanges.OrderCanceled || event.ExecutedQty != 0.0004 {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	_, err = parseOrderEvent([]byte(`{"c":"` + ordersChannel + `","d":{"i":"C02__3","s":9}}`))
+	if err == nil {
+		t.Error("expected an error with an unknown status")
+	}
+}
@@ -0,0 +1,165 @@
+package mexc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Shared by every client so connections are reused between calls
+var httpClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         (&net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// MEXC allows a weight of 500 every 10 seconds per IP and endpoint. The
+// limiter is global to stay under it whatever the endpoint.
+var sharedLimiter = newLimiter(500, 10*time.Second)
+
+// Weight of the endpoints heavier than 1
+var endpointWeights = map[string]float64{
+	"/api/v3/account":      10,
+	"/api/v3/exchangeInfo": 10,
+	"/api/v3/myTrades":     10,
+	"/api/v3/openOrders":   3,
+	"/api/v3/order":        2,
+}
+
+func endpointWeight(endpoint string) float64 {
+	if weight, ok := endpointWeights[endpoint]; ok {
+		return weight
+	}
+	return 1
+}
+
+const maxAttempts = 5
+
+// First backoff delay, doubled on each retry up to maxRetryDelay
+var (
+	retryDelay    = 500 * time.Millisecond
+	maxRetryDelay = 10 * time.Second
+)
+
+// limiter is a token bucket: capacity tokens, refilled over period. After a
+// 429 it holds every request until the time given by Retry-After.
+type limiter struct {
+	mu        sync.Mutex
+	tokens    float64
+	capacity  float64
+	perSecond float64
+	last      time.Time
+	blocked   time.Time
+}
+
+func newLimiter(capacity float64, period time.Duration) *limiter {
+	return &limiter{
+		tokens:    capacity,
+		capacity:  capacity,
+		perSecond: capacity / period.Seconds(),
+		last:      time.Now(),
+	}
+}
+
+// wait blocks until weight tokens are available and takes them, or until ctx
+// is done
+func (l *limiter) wait(ctx context.Context, weight float64) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+
+		var delay time.Duration
+		if now.Before(l.blocked) {
+			delay = l.blocked.Sub(now)
+		} else {
+			l.tokens += now.Sub(l.last).Seconds() * l.perSecond
+			if l.tokens > l.capacity {
+				l.tokens = l.capacity
+			}
+			l.last = now
+
+			if l.tokens >= weight {
+				l.tokens -= weight
+				l.mu.Unlock()
+				return nil
+			}
+			delay = time.Duration((weight - l.tokens) / l.perSecond * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		err := sleep(ctx, delay)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for d, or returns the error of ctx once done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// block holds every request for d
+func (l *limiter) block(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(l.blocked) {
+		l.blocked = until
+	}
+}
+
+// Delay asked by a Retry-After header, in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date), true
+	}
+	return 0, false
+}
+
+func backoff(attempt int) time.Duration {
+	delay := retryDelay << attempt
+	if delay > maxRetryDelay || delay <= 0 {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// Whether a failed call may be sent again. A 429 was rejected before being
+// processed, so it is safe for every method. Server errors and network
+// failures may have placed or canceled an order, only reads are retried.
+func retryable(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return method == http.MethodGet && errors.As(err, &netErr)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return method == http.MethodGet && resp.StatusCode >= 500
+}
@@ -0,0 +1,168 @@
+package mexc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"main/exchanges"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// throttledServer answers with the given statuses in turn, then 200
+func throttledServer(t *testing.T, statuses []int, header http.Header) (*Client, *atomic.Int32) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[n-1])
+			_, _ = io.WriteString(w, `{"code":429,"msg":"Too Many Requests"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{}`)
+	}))
+	t.Cleanup(server.Close)
+
+	previous := retryDelay
+	retryDelay = time.Millisecond
+	t.Cleanup(func() { retryDelay = previous })
+
+	return &Client{BaseURL: server.URL, limiter: newLimiter(500, 10*time.Second)}, &calls
+}
+
+func TestTransport_RetryAfter(t *testing.T) {
+	client, calls := throttledServer(t, []int{http.StatusTooManyRequests}, http.Header{"Retry-After": {"1"}})
+
+	start := time.Now()
+	_, err := client.sendRequest(context.Background(), "POST", "/api/v3/order", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 calls, got %d", calls.Load())
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected to wait for Retry-After, waited %s", elapsed)
+	}
+}
+
+func TestTransport_BackoffOnServerError(t *testing.T) {
+	client, calls := throttledServer(t, []int{http.StatusBadGateway, http.StatusServiceUnavailable}, nil)
+
+	_, err := client.sendRequest(context.Background(), "GET", "/api/v3/ticker/price", "symbol=BTCUSDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", calls.Load())
+	}
+}
+
+func TestTransport_NoRetryOfOrdersOnServerError(t *testing.T) {
+	client, calls := throttledServer(t, []int{http.StatusServiceUnavailable}, nil)
+
+	// The order may have been placed, sending it again could double it
+	_, err := client.sendRequest(context.Background(), "POST", "/api/v3/order", "")
+	if err == nil {
+		t.Fatal("expected the server error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestTransport_GiveUp(t *testing.T) {
+	statuses := make([]int, maxAttempts+1)
+	for i := range statuses {
+		statuses[i] = http.StatusTooManyRequests
+	}
+	client, calls := throttledServer(t, statuses, nil)
+
+	_, err := client.sendRequest(context.Background(), "GET", "/api/v3/ping", "")
+	if err == nil {
+		t.Fatal("expected an error once attempts are exhausted")
+	}
+	if calls.Load() != maxAttempts {
+		t.Errorf("expected %d calls, got %d", maxAttempts, calls.Load())
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	// 2 tokens per 100ms: the 3rd and 4th calls wait for the refill
+	l := newLimiter(2, 100*time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_ = l.wait(context.Background(), 1)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected the limiter to wait, took %s", elapsed)
+	}
+
+	l.block(50 * time.Millisecond)
+	start = time.Now()
+	_ = l.wait(context.Background(), 0)
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected the block to hold the call, took %s", elapsed)
+	}
+}
+
+func TestTransport_ErrorKinds(t *testing.T) {
+	client, _ := throttledServer(t, []int{http.StatusServiceUnavailable}, nil)
+	_, err := client.sendRequest(context.Background(), "POST", "/api/v3/order", "")
+	if !errors.Is(err, exchanges.ErrNetwork) {
+		t.Errorf("expected a network error, got %v", err)
+	}
+
+	apiErr := apiError(http.StatusBadRequest, []byte(`{"code":30004,"msg":"Insufficient position"}`))
+	if !errors.Is(apiErr, exchanges.ErrInsufficientBalance) || apiErr.Code != "30004" {
+		t.Errorf("expected an insufficient balance error, got %v", apiErr)
+	}
+
+	apiErr = apiError(http.StatusBadRequest, []byte(`{"code":-2013,"msg":"Order does not exist."}`))
+	if !errors.Is(apiErr, exchanges.ErrOrderNotFound) {
+		t.Errorf("expected an order not found error, got %v", apiErr)
+	}
+}
+
+func TestTransport_Context(t *testing.T) {
+	hung := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-hung:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(hung)
+	client := &Client{BaseURL: server.URL, limiter: newLimiter(500, 10*time.Second)}
+
+	// Canceled by the caller, e.g. on CTRL + C
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := client.sendRequest(ctx, "POST", "/api/v3/order", "")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the request canceled, got %v", err)
+	}
+
+	// Bounded by the call deadline otherwise
+	callTimeout := exchanges.CallTimeout
+	exchanges.CallTimeout = 50 * time.Millisecond
+	defer func() { exchanges.CallTimeout = callTimeout }()
+
+	start := time.Now()
+	_, err = client.sendRequest(context.Background(), "GET", "/api/v3/ticker/price", "symbol=BTCUSDC")
+	if !errors.Is(err, context.DeadlineExceeded) || !exchanges.Temporary(err) {
+		t.Errorf("expected a temporary deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the retries to stop at the deadline, took %s", elapsed)
+	}
+}
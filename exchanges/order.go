@@ -0,0 +1,50 @@
+package exchanges
+
+type OrderStatus string
+
+const (
+	OrderNew             OrderStatus = "NEW"
+	OrderPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
+	OrderFilled          OrderStatus = "FILLED"
+	OrderCanceled        OrderStatus = "CANCELED"
+	OrderRejected        OrderStatus = "REJECTED"
+	OrderExpired         OrderStatus = "EXPIRED"
+)
+
+// Order is a limit order as every exchange client returns it, whatever the
+// shape of the exchange API. Fields the exchange did not send are zero.
+type Order struct {
+	Id          string      `json:"id"`
+	ClientId    string      `json:"clientId"`
+	Symbol      Symbol      `json:"symbol"`
+	Side        string      `json:"side"` // BUY or SELL
+	Status      OrderStatus `json:"status"`
+	Price       float64     `json:"price"`
+	OrigQty     float64     `json:"origQty"`
+	ExecutedQty float64     `json:"executedQty"`
+	QuoteQty    float64     `json:"quoteQty"` // executed amount in quote asset
+	Fee         float64     `json:"fee"`
+	FeeAsset    string      `json:"feeAsset"`
+	CreatedAt   int64       `json:"createdAt"` // unix milliseconds
+	UpdatedAt   int64       `json:"updatedAt"`
+}
+
+func (o *Order) IsFilled() bool {
+	return o.Status == OrderFilled
+}
+
+// IsActive reports whether the order can still be filled
+func (o *Order) IsActive() bool {
+	return o.Status == OrderNew || o.Status == OrderPartiallyFilled
+}
+
+// OrderEvent is a change of an order pushed by an exchange stream
+type OrderEvent struct {
+	OrderId     string
+	ClientId    string
+	Pair        string // as named by the exchange, e.g. BTCUSDC
+	Side        string
+	Status      OrderStatus
+	ExecutedQty float64
+	Time        int64 // unix milliseconds
+}
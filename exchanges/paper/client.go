@@ -0,0 +1,401 @@
+package paper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"log"
+	"main/database"
+	"main/exchanges"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The rules of MEXC for BTC/USDC, so paper orders fail where real ones would
+var defaultFilters = exchanges.SymbolFilters{TickSize: 0.01, StepSize: 0.000001, MinNotional: 1}
+
+// symbolFilters returns the rules of the paper orders, those of BTC/USDC
+// unless PAPER_TICK_SIZE, PAPER_STEP_SIZE or PAPER_MIN_NOTIONAL set the ones
+// of the pair traded
+func symbolFilters() (exchanges.SymbolFilters, error) {
+	filters := defaultFilters
+	for key, field := range map[string]*float64{
+		"PAPER_TICK_SIZE":    &filters.TickSize,
+		"PAPER_STEP_SIZE":    &filters.StepSize,
+		"PAPER_MIN_NOTIONAL": &filters.MinNotional,
+	} {
+		str := os.Getenv(key)
+		if str == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(str, 64)
+		if err != nil || value < 0 {
+			return filters, fmt.Errorf("%s must be a positive number: %s", key, str)
+		}
+		*field = value
+	}
+	return filters, nil
+}
+
+// Client is a local exchange: balances and limit orders live in the
+// database and orders fill when an observed price crosses their limit.
+type Client struct {
+	Source PriceSource
+}
+
+func NewClient() *Client {
+	var source PriceSource
+	switch strings.ToUpper(os.Getenv("PAPER_FEED")) {
+	case "CSV":
+		source = NewCSVSource(os.Getenv("PAPER_CSV"))
+	default:
+		source = NewMEXCSource("https://api.mexc.com")
+	}
+
+	return &Client{Source: source}
+}
+
+// SetBaseURL sets the URL of the MEXC ticker when it is the price source
+func (c *Client) SetBaseURL(url string) {
+	if source, ok := c.Source.(*MEXCSource); ok {
+		source.client.SetBaseURL(url)
+	}
+}
+
+func balanceKey(asset string) string {
+	return "paper_balance_" + asset
+}
+
+// Returns the free balance of asset. USD stablecoins are seeded with
+// PAPER_BALANCE_USD (1000 by default) the first time.
+func (c *Client) balance(asset string) (float64, error) {
+	value, err := database.CfgGet(balanceKey(asset))
+	if err != nil {
+		return 0, err
+	}
+
+	if value == "" {
+		initial := 0.0
+		if asset == "USDC" || asset == "USDT" || asset == "USD" {
+			initial = 1000
+			if str := os.Getenv("PAPER_BALANCE_USD"); str != "" {
+				initial, err = strconv.ParseFloat(str, 64)
+				if err != nil {
+					return 0, fmt.Errorf("PAPER_BALANCE_USD must be a number: %v", err)
+				}
+			}
+		}
+		return initial, c.setBalance(asset, initial)
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
+
+func (c *Client) setBalance(asset string, amount float64) error {
+	return database.CfgSet(balanceKey(asset), strconv.FormatFloat(amount, 'f', -1, 64))
+}
+
+func (c *Client) addBalance(asset string, amount float64) error {
+	balance, err := c.balance(asset)
+	if err != nil {
+		return err
+	}
+	return c.setBalance(asset, balance+amount)
+}
+
+// Fills every open order of symbol crossed by price. Orders fill at their
+// limit price.
+func (c *Client) match(symbol exchanges.Symbol, price float64) error {
+	orders, err := database.PaperOrderListByStatus(database.PaperNew)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if order.Symbol != symbol.String() {
+			continue
+		}
+
+		if order.Side == "BUY" && price <= order.Price {
+			err = c.addBalance(symbol.Base, order.Quantity)
+		} else if order.Side == "SELL" && price >= order.Price {
+			err = c.addBalance(symbol.Quote, order.Price*order.Quantity)
+		} else {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		err = database.PaperOrderUpdateStatus(order.Id, database.PaperFilled)
+		if err != nil {
+			return err
+		}
+		log.Printf("Paper order %d filled: %s %.6f @ %.2f", order.Id, order.Side, order.Quantity, order.Price)
+	}
+
+	return nil
+}
+
+// CheckConnection checks the price source. A replay is not moved on, its
+// rows are only read with the orders matched.
+func (c *Client) CheckConnection(ctx context.Context) error {
+	var err error
+	if checker, ok := c.Source.(Checker); ok {
+		err = checker.Check(ctx)
+	} else {
+		_, err = c.Source.Price(ctx, exchanges.DefaultSymbol)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read paper price source: %w", err)
+	}
+
+	color.Green("Connected to PAPER exchange successfully")
+	fmt.Println("")
+	return nil
+}
+
+func (c *Client) GetBalance(ctx context.Context, asset string) (float64, error) {
+	color.Blue("Checking paper %s balance...", asset)
+	return c.balance(asset)
+}
+
+// GetKlines reads the klines of the price source, when it has any
+func (c *Client) GetKlines(ctx context.Context, symbol exchanges.Symbol, interval time.Duration, limit int) ([]exchanges.Kline, error) {
+	source, ok := c.Source.(KlineSource)
+	if !ok {
+		return nil, fmt.Errorf("%w: no klines from the paper price source", exchanges.ErrInvalidParameter)
+	}
+	return source.GetKlines(ctx, symbol, interval, limit)
+}
+
+// GetLastPrice reads the price source then fills the orders it crosses
+func (c *Client) GetLastPrice(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	price, err := c.Source.Price(ctx, symbol)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching %s price: %w", symbol, err)
+	}
+
+	err = c.match(symbol, price)
+	if err != nil {
+		return 0, fmt.Errorf("error matching paper orders: %v", err)
+	}
+
+	return price, nil
+}
+
+// CreateOrder locks the funds of a limit order, quote for a BUY and base for
+// a SELL
+func (c *Client) CreateOrder(ctx context.Context, symbol exchanges.Symbol, side string, price, quantity, clientId string) (*exchanges.Order, error) {
+	side = strings.ToUpper(side)
+
+	priceFloat, err := strconv.ParseFloat(price, 64)
+	if err != nil || priceFloat <= 0 {
+		return nil, fmt.Errorf("%w: price %s", exchanges.ErrInvalidParameter, price)
+	}
+	quantityFloat, err := strconv.ParseFloat(quantity, 64)
+	if err != nil || quantityFloat <= 0 {
+		return nil, fmt.Errorf("%w: quantity %s", exchanges.ErrInvalidParameter, quantity)
+	}
+
+	filters, err := symbolFilters()
+	if err != nil {
+		return nil, err
+	}
+	err = filters.Validate(priceFloat, quantityFloat)
+	if err != nil {
+		return nil, fmt.Errorf("order rejected: %w: %v", exchanges.ErrInvalidParameter, err)
+	}
+
+	var asset string
+	var amount float64
+	switch side {
+	case "BUY":
+		asset, amount = symbol.Quote, priceFloat*quantityFloat
+	case "SELL":
+		asset, amount = symbol.Base, quantityFloat
+	default:
+		return nil, fmt.Errorf("%w: side %s", exchanges.ErrInvalidParameter, side)
+	}
+
+	free, err := c.balance(asset)
+	if err != nil {
+		return nil, err
+	}
+	if free < amount {
+		return nil, fmt.Errorf("%w: %s %f < %f", exchanges.ErrInsufficientBalance, asset, free, amount)
+	}
+	err = c.setBalance(asset, free-amount)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	order := &database.PaperOrder{
+		ClientId:  clientId,
+		Symbol:    symbol.String(),
+		Side:      side,
+		Price:     priceFloat,
+		Quantity:  quantityFloat,
+		Status:    database.PaperNew,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	id, err := database.PaperOrderNew(order)
+	if err != nil {
+		return nil, err
+	}
+	order.Id = int(id)
+
+	return toOrder(order)
+}
+
+func (c *Client) getOrder(id string) (*database.PaperOrder, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: order id %s", exchanges.ErrInvalidParameter, id)
+	}
+
+	order, err := database.PaperOrderGetById(idInt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("order %s: %w", id, exchanges.ErrOrderNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting order %s: %v", id, err)
+	}
+
+	return order, nil
+}
+
+func (c *Client) GetOrderById(ctx context.Context, symbol exchanges.Symbol, id string) (*exchanges.Order, error) {
+	order, err := c.getOrder(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toOrder(order)
+}
+
+func (c *Client) GetOrderByClientId(ctx context.Context, symbol exchanges.Symbol, clientId string) (*exchanges.Order, error) {
+	order, err := database.PaperOrderGetByClientId(clientId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("order %s: %w", clientId, exchanges.ErrOrderNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting order %s: %v", clientId, err)
+	}
+
+	return toOrder(order)
+}
+
+// CancelOrder releases the funds locked by an open order
+func (c *Client) CancelOrder(ctx context.Context, symbol exchanges.Symbol, orderID string) (*exchanges.Order, error) {
+	order, err := c.getOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != database.PaperNew {
+		return nil, fmt.Errorf("error canceling order %s: order is %s", orderID, order.Status)
+	}
+
+	orderSymbol, err := exchanges.ParseSymbol(order.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Side == "BUY" {
+		err = c.addBalance(orderSymbol.Quote, order.Price*order.Quantity)
+	} else {
+		err = c.addBalance(orderSymbol.Base, order.Quantity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.PaperOrderUpdateStatus(order.Id, database.PaperCanceled)
+	if err != nil {
+		return nil, err
+	}
+	order.Status = database.PaperCanceled
+
+	color.Green("Order %s canceled successfully", orderID)
+	return toOrder(order)
+}
+
+func (c *Client) GetOpenOrders(ctx context.Context, symbol exchanges.Symbol) ([]exchanges.Order, error) {
+	orders, err := database.PaperOrderListByStatus(database.PaperNew)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching open orders: %v", err)
+	}
+
+	list := make([]exchanges.Order, 0, len(orders))
+	for i := range orders {
+		if orders[i].Symbol != symbol.String() {
+			continue
+		}
+		order, err := toOrder(&orders[i])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *order)
+	}
+
+	return list, nil
+}
+
+// GetOrderTrades returns the single fill of a filled order. Paper trading
+// charges no fee.
+func (c *Client) GetOrderTrades(ctx context.Context, symbol exchanges.Symbol, orderID string) ([]exchanges.Trade, error) {
+	order, err := c.GetOrderById(ctx, symbol, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !order.IsFilled() {
+		return []exchanges.Trade{}, nil
+	}
+
+	return []exchanges.Trade{{
+		Id:       orderID,
+		OrderId:  orderID,
+		Price:    order.Price,
+		Qty:      order.ExecutedQty,
+		QuoteQty: order.QuoteQty,
+		FeeAsset: symbol.Quote,
+		Time:     order.UpdatedAt,
+	}}, nil
+}
+
+func (c *Client) GetSymbolFilters(ctx context.Context, symbol exchanges.Symbol) (exchanges.SymbolFilters, error) {
+	return symbolFilters()
+}
+
+// Orders fill at once and entirely at their limit price, without fees
+func toOrder(order *database.PaperOrder) (*exchanges.Order, error) {
+	symbol, err := exchanges.ParseSymbol(order.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	executed := 0.0
+	if order.Status == database.PaperFilled {
+		executed = order.Quantity
+	}
+
+	return &exchanges.Order{
+		Id:          strconv.Itoa(order.Id),
+		ClientId:    order.ClientId,
+		Symbol:      symbol,
+		Side:        order.Side,
+		Status:      exchanges.OrderStatus(order.Status),
+		Price:       order.Price,
+		OrigQty:     order.Quantity,
+		ExecutedQty: executed,
+		QuoteQty:    executed * order.Price,
+		CreatedAt:   order.CreatedAt,
+		UpdatedAt:   order.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,242 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"main/database"
+	"main/exchanges"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var btcusdc = exchanges.DefaultSymbol
+
+type fixedSource struct {
+	price float64
+}
+
+func (s *fixedSource) Price(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	return s.price, nil
+}
+
+// Points the database to a temporary home so tests never touch the real one
+func setup(t *testing.T) (*Client, *fixedSource) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PAPER_BALANCE_USD", "1000")
+
+	err := database.InitDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &fixedSource{price: 100000}
+	return &Client{Source: source}, source
+}
+
+func TestGetBalance(t *testing.T) {
+	client, _ := setup(t)
+
+	balance, err := client.GetBalance(context.Background(), "USDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 1000 {
+		t.Errorf("expected initial balance 1000, got %v", balance)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	client, source := setup(t)
+
+	// Buy 0.005 BTC at 99000 locks 495 USDC
+	buy, err := client.CreateOrder(context.Background(), btcusdc, "BUY", "99000", "0.005", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buyId := buy.Id
+
+	balance, _ := client.GetBalance(context.Background(), "USDC")
+	if balance != 505 {
+		t.Errorf("expected 505 USDC left, got %v", balance)
+	}
+
+	// Price above the limit, still open
+	_, _ = client.GetLastPrice(context.Background(), btcusdc)
+	order, _ := client.GetOrderById(context.Background(), btcusdc, buyId)
+	if order.IsFilled() {
+		t.Fatal("buy should not be filled at 100000")
+	}
+
+	// Selling BTC not yet bought must fail
+	_, err = client.CreateOrder(context.Background(), btcusdc, "SELL", "101000", "0.005", "")
+	if !errors.Is(err, exchanges.ErrInsufficientBalance) {
+		t.Fatalf("expected an insufficient balance error, got %v", err)
+	}
+
+	source.price = 98500
+	_, _ = client.GetLastPrice(context.Background(), btcusdc)
+	order, _ = client.GetOrderById(context.Background(), btcusdc, buyId)
+	if !order.IsFilled() {
+		t.Fatalf("buy should be filled at 98500: %+v", order)
+	}
+
+	sell, err := client.CreateOrder(context.Background(), btcusdc, "SELL", "101000", "0.005", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source.price = 101500
+	_, _ = client.GetLastPrice(context.Background(), btcusdc)
+	order, _ = client.GetOrderById(context.Background(), btcusdc, sell.Id)
+	if !order.IsFilled() {
+		t.Fatalf("sell should be filled at 101500: %+v", order)
+	}
+
+	trades, err := client.GetOrderTrades(context.Background(), btcusdc, sell.Id)
+	if err != nil || len(trades) != 1 || trades[0].QuoteQty != 505 || trades[0].Fee != 0 {
+		t.Errorf("expected a single fill of 505 USDC without fee, got %+v %v", trades, err)
+	}
+
+	// 505 + 0.005 * 101000
+	balance, _ = client.GetBalance(context.Background(), "USDC")
+	if balance != 1010 {
+		t.Errorf("expected 1010 USDC after the cycle, got %v", balance)
+	}
+}
+
+func TestMinNotional(t *testing.T) {
+	client, _ := setup(t)
+
+	// 0.00001 * 90000 = 0.9 USDC
+	_, err := client.CreateOrder(context.Background(), btcusdc, "BUY", "90000", "0.00001", "")
+	if err == nil {
+		t.Fatal("expected an order below the minimum notional to be rejected")
+	}
+
+	balance, _ := client.GetBalance(context.Background(), "USDC")
+	if balance != 1000 {
+		t.Errorf("expected balance untouched, got %v", balance)
+	}
+}
+
+func TestSymbolFilters(t *testing.T) {
+	client, source := setup(t)
+	dogeusdc := exchanges.Symbol{Base: "DOGE", Quote: "USDC"}
+
+	// The rules of BTC/USDC would round a DOGE price to 0.12
+	t.Setenv("PAPER_TICK_SIZE", "0.00001")
+	t.Setenv("PAPER_STEP_SIZE", "1")
+	filters, err := client.GetSymbolFilters(context.Background(), dogeusdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := exchanges.SymbolFilters{TickSize: 0.00001, StepSize: 1, MinNotional: 1}
+	if filters != expected {
+		t.Errorf("expected %+v, got %+v", expected, filters)
+	}
+	if price := filters.FormatPrice(0.123456); price != "0.12346" {
+		t.Errorf("expected 0.12346, got %s", price)
+	}
+
+	source.price = 0.12
+	_, err = client.CreateOrder(context.Background(), dogeusdc, "BUY", "0.12346", "100", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.GetLastPrice(context.Background(), dogeusdc)
+	doge, _ := client.GetBalance(context.Background(), "DOGE")
+	if doge != 100 {
+		t.Errorf("expected 100 DOGE bought, got %v", doge)
+	}
+
+	t.Setenv("PAPER_TICK_SIZE", "tick")
+	_, err = client.GetSymbolFilters(context.Background(), dogeusdc)
+	if err == nil {
+		t.Error("expected an error with an invalid PAPER_TICK_SIZE")
+	}
+}
+
+func TestGetOrderByClientId(t *testing.T) {
+	client, _ := setup(t)
+
+	buy, err := client.CreateOrder(context.Background(), btcusdc, "BUY", "90000", "0.01", "bs-1a2b-7-buy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := client.GetOrderByClientId(context.Background(), btcusdc, "bs-1a2b-7-buy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Id != buy.Id || order.ClientId != "bs-1a2b-7-buy" {
+		t.Errorf("expected order %s, got %+v", buy.Id, order)
+	}
+
+	_, err = client.GetOrderByClientId(context.Background(), btcusdc, "bs-1a2b-8-buy")
+	if !errors.Is(err, exchanges.ErrOrderNotFound) {
+		t.Errorf("expected an order not found error, got %v", err)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	client, _ := setup(t)
+
+	buy, err := client.CreateOrder(context.Background(), btcusdc, "BUY", "90000", "0.01", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open, _ := client.GetOpenOrders(context.Background(), btcusdc)
+	if len(open) != 1 || open[0].Id != buy.Id {
+		t.Errorf("expected order in open orders, got %+v", open)
+	}
+
+	_, err = client.CancelOrder(context.Background(), btcusdc, buy.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, _ := client.GetBalance(context.Background(), "USDC")
+	if balance != 1000 {
+		t.Errorf("expected balance restored to 1000, got %v", balance)
+	}
+
+	_, err = client.CancelOrder(context.Background(), btcusdc, buy.Id)
+	if err == nil {
+		t.Error("expected an error when canceling twice")
+	}
+}
+
+func TestCSVSource(t *testing.T) {
+	setup(t)
+
+	path := filepath.Join(t.TempDir(), "prices.csv")
+	err := os.WriteFile(path, []byte("time,price\n1,100000\n2,99000.5\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Checking the connection leaves the rows to the orders
+	client := &Client{Source: NewCSVSource(path)}
+	for i := 0; i < 3; i++ {
+		client.CheckConnection(context.Background())
+	}
+
+	source := NewCSVSource(path)
+	for _, expected := range []float64{100000, 99000.5} {
+		price, err := source.Price(context.Background(), btcusdc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price != expected {
+			t.Errorf("expected %v, got %v", expected, price)
+		}
+	}
+
+	// The position survives a new source on the same database
+	_, err = NewCSVSource(path).Price(context.Background(), btcusdc)
+	if err == nil {
+		t.Error("expected the end of the replay")
+	}
+}
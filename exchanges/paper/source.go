@@ -0,0 +1,132 @@
+package paper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"main/database"
+	"main/exchanges"
+	"main/exchanges/mexc"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PriceSource feeds the paper exchange with prices
+type PriceSource interface {
+	Price(ctx context.Context, symbol exchanges.Symbol) (float64, error)
+}
+
+// Checker is a price source able to tell it works without moving on, the
+// connection check reads a price from the others
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// KlineSource is a price source giving past klines too
+type KlineSource interface {
+	GetKlines(ctx context.Context, symbol exchanges.Symbol, interval time.Duration, limit int) ([]exchanges.Kline, error)
+}
+
+// MEXCSource reads the real public MEXC ticker
+type MEXCSource struct {
+	client *mexc.Client
+}
+
+func NewMEXCSource(baseURL string) *MEXCSource {
+	client := mexc.NewClient()
+	client.SetBaseURL(baseURL)
+	return &MEXCSource{client: client}
+}
+
+func (s *MEXCSource) Price(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	return s.client.GetLastPrice(ctx, symbol)
+}
+
+func (s *MEXCSource) GetKlines(ctx context.Context, symbol exchanges.Symbol, interval time.Duration, limit int) ([]exchanges.Kline, error) {
+	return s.client.GetKlines(ctx, symbol, interval, limit)
+}
+
+// CSVSource replays prices from a CSV file, one row per call, whatever the
+// symbol. The price is the last column of each row, rows that do not parse
+// (headers) are skipped. The position is kept in the database so the replay
+// goes on across runs.
+type CSVSource struct {
+	Path   string
+	prices []float64
+}
+
+const csvPositionKey = "paper_csv_position"
+
+func NewCSVSource(path string) *CSVSource {
+	return &CSVSource{Path: path}
+}
+
+func (s *CSVSource) load() error {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return fmt.Errorf("error opening price file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		columns := strings.Split(strings.TrimSpace(scanner.Text()), ",")
+		price, err := strconv.ParseFloat(strings.TrimSpace(columns[len(columns)-1]), 64)
+		if err != nil {
+			continue
+		}
+		s.prices = append(s.prices, price)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading price file: %v", err)
+	}
+	if len(s.prices) == 0 {
+		return fmt.Errorf("no price found in %s", s.Path)
+	}
+
+	return nil
+}
+
+// position returns the row the replay is at, an error once it reached the
+// end
+func (s *CSVSource) position() (int, error) {
+	if s.prices == nil {
+		if err := s.load(); err != nil {
+			return 0, err
+		}
+	}
+
+	positionStr, err := database.CfgGet(csvPositionKey)
+	if err != nil {
+		return 0, err
+	}
+	position, _ := strconv.Atoi(positionStr)
+
+	if position >= len(s.prices) {
+		return 0, fmt.Errorf("end of price replay %s after %d rows", s.Path, len(s.prices))
+	}
+	return position, nil
+}
+
+// Check tells whether a row is left, without consuming it
+func (s *CSVSource) Check(ctx context.Context) error {
+	_, err := s.position()
+	return err
+}
+
+func (s *CSVSource) Price(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	position, err := s.position()
+	if err != nil {
+		return 0, err
+	}
+
+	err = database.CfgSet(csvPositionKey, strconv.Itoa(position+1))
+	if err != nil {
+		return 0, err
+	}
+
+	return s.prices[position], nil
+}
@@ -0,0 +1,41 @@
+package exchanges
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Symbol is a trading pair. It is written BASE/QUOTE in bot.conf and in the
+// database, each exchange client formats it the way its API expects.
+type Symbol struct {
+	Base  string
+	Quote string
+}
+
+// DefaultSymbol is the pair used when none is configured, and the pair of
+// every cycle created before symbols existed
+var DefaultSymbol = Symbol{Base: "BTC", Quote: "USDC"}
+
+// ParseSymbol reads a pair like "ETH/USDT". "-" and "_" are accepted as
+// separators too.
+func ParseSymbol(str string) (Symbol, error) {
+	str = strings.ToUpper(strings.TrimSpace(str))
+
+	parts := strings.FieldsFunc(str, func(r rune) bool {
+		return r == '/' || r == '-' || r == '_'
+	})
+	if len(parts) != 2 {
+		return Symbol{}, fmt.Errorf("invalid symbol %q, expected BASE/QUOTE like BTC/USDC", str)
+	}
+
+	return Symbol{Base: parts[0], Quote: parts[1]}, nil
+}
+
+func (s Symbol) String() string {
+	return s.Join("/")
+}
+
+// Join returns base and quote separated by sep, e.g. Join("") is "BTCUSDC"
+func (s Symbol) Join(sep string) string {
+	return s.Base + sep + s.Quote
+}
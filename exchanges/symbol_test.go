@@ -0,0 +1,36 @@
+package exchanges
+
+import "testing"
+
+func TestParseSymbol(t *testing.T) {
+	for str, expected := range map[string]Symbol{
+		"BTC/USDC":  {Base: "BTC", Quote: "USDC"},
+		"eth-usdt":  {Base: "ETH", Quote: "USDT"},
+		" SOL_USDC": {Base: "SOL", Quote: "USDC"},
+	} {
+		symbol, err := ParseSymbol(str)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if symbol != expected {
+			t.Errorf("%s: expected %v, got %v", str, expected, symbol)
+		}
+	}
+
+	for _, str := range []string{"", "BTCUSDC", "BTC/USDC/EUR"} {
+		if _, err := ParseSymbol(str); err == nil {
+			t.Errorf("%q: expected an error", str)
+		}
+	}
+}
+
+func TestSymbolString(t *testing.T) {
+	symbol := Symbol{Base: "ETH", Quote: "USDT"}
+
+	if symbol.String() != "ETH/USDT" {
+		t.Errorf("expected ETH/USDT, got %s", symbol.String())
+	}
+	if symbol.Join("") != "ETHUSDT" {
+		t.Errorf("expected ETHUSDT, got %s", symbol.Join(""))
+	}
+}
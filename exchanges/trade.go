@@ -0,0 +1,31 @@
+package exchanges
+
+// Trade is one fill of an order and the commission charged for it
+type Trade struct {
+	Id       string  `json:"id"`
+	OrderId  string  `json:"orderId"`
+	Price    float64 `json:"price"`
+	Qty      float64 `json:"qty"`
+	QuoteQty float64 `json:"quoteQty"`
+	Fee      float64 `json:"fee"`
+	FeeAsset string  `json:"feeAsset"`
+	Time     int64   `json:"time"` // unix milliseconds
+}
+
+// TotalFee sums the commissions of the trades of an order. Exchanges charge
+// every fill of an order in the same asset, trades in another asset than
+// the first one are ignored.
+func TotalFee(trades []Trade) (float64, string) {
+	if len(trades) == 0 {
+		return 0, ""
+	}
+
+	asset := trades[0].FeeAsset
+	fee := 0.0
+	for _, trade := range trades {
+		if trade.FeeAsset == asset {
+			fee += trade.Fee
+		}
+	}
+	return fee, asset
+}
@@ -0,0 +1,19 @@
+package exchanges
+
+import "testing"
+
+func TestTotalFee(t *testing.T) {
+	fee, asset := TotalFee(nil)
+	if fee != 0 || asset != "" {
+		t.Errorf("expected no fee, got %v %s", fee, asset)
+	}
+
+	fee, asset = TotalFee([]Trade{
+		{Fee: 0.05, FeeAsset: "USDC"},
+		{Fee: 0.03, FeeAsset: "USDC"},
+		{Fee: 0.001, FeeAsset: "BNB"},
+	})
+	if asset != "USDC" || fee != 0.08 {
+		t.Errorf("expected 0.08 USDC, got %v %s", fee, asset)
+	}
+}
@@ -0,0 +1,93 @@
+package feed
+
+import (
+	"context"
+	"main/exchanges"
+	"sync"
+	"time"
+)
+
+// Price is the last price of a symbol and when it was read
+type Price struct {
+	Value float64
+	Time  time.Time
+}
+
+// PriceFeed is the one source of the last prices of a process
+type PriceFeed interface {
+	// Latest returns the price of symbol, read again once older than the
+	// max age of the feed
+	Latest(ctx context.Context, symbol exchanges.Symbol) (Price, error)
+	// Subscribe returns a channel receiving each new price of symbol, until
+	// cancel is called. A slow subscriber only misses the older prices.
+	Subscribe(symbol exchanges.Symbol) (prices <-chan Price, cancel func())
+	// Run keeps the price of symbol fresh until ctx is done
+	Run(ctx context.Context, symbol exchanges.Symbol)
+}
+
+// Fetcher reads a price on demand, every exchange client is one
+type Fetcher interface {
+	GetLastPrice(ctx context.Context, symbol exchanges.Symbol) (float64, error)
+}
+
+// cache keeps the last price of each symbol and publishes the new ones
+type cache struct {
+	mu          sync.Mutex
+	prices      map[exchanges.Symbol]Price
+	subscribers map[exchanges.Symbol]map[chan Price]struct{}
+}
+
+func newCache() cache {
+	return cache{
+		prices:      map[exchanges.Symbol]Price{},
+		subscribers: map[exchanges.Symbol]map[chan Price]struct{}{},
+	}
+}
+
+// fresh returns the price of symbol if it is younger than maxAge
+func (c *cache) fresh(symbol exchanges.Symbol, maxAge time.Duration) (Price, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	price, ok := c.prices[symbol]
+	if !ok || time.Since(price.Time) > maxAge {
+		return Price{}, false
+	}
+	return price, true
+}
+
+func (c *cache) publish(symbol exchanges.Symbol, value float64) Price {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	price := Price{Value: value, Time: time.Now()}
+	c.prices[symbol] = price
+
+	for ch := range c.subscribers[symbol] {
+		// Replace the price the subscriber did not read yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- price
+	}
+
+	return price
+}
+
+func (c *cache) Subscribe(symbol exchanges.Symbol) (<-chan Price, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan Price, 1)
+	if c.subscribers[symbol] == nil {
+		c.subscribers[symbol] = map[chan Price]struct{}{}
+	}
+	c.subscribers[symbol][ch] = struct{}{}
+
+	return ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subscribers[symbol], ch)
+	}
+}
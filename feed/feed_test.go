@@ -0,0 +1,133 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"main/exchanges"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeFetcher answers price, counting the reads
+type fakeFetcher struct {
+	price float64
+	calls atomic.Int32
+}
+
+func (f *fakeFetcher) GetLastPrice(ctx context.Context, symbol exchanges.Symbol) (float64, error) {
+	f.calls.Add(1)
+	return f.price, nil
+}
+
+// fakeStreamer sends each value of prices, then drops
+type fakeStreamer struct {
+	prices []float64
+}
+
+func (f *fakeStreamer) StreamPrices(ctx context.Context, symbol exchanges.Symbol, prices chan<- float64) error {
+	for _, price := range f.prices {
+		select {
+		case prices <- price:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	f.prices = nil
+	<-ctx.Done()
+	return errors.New("dropped")
+}
+
+func TestPoller_Latest(t *testing.T) {
+	fetcher := &fakeFetcher{price: 90000}
+	poller := NewPoller(fetcher, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		price, err := poller.Latest(context.Background(), exchanges.DefaultSymbol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price.Value != 90000 {
+			t.Errorf("expected 90000, got %v", price.Value)
+		}
+	}
+	if fetcher.calls.Load() != 1 {
+		t.Errorf("expected the price read once while fresh, got %d reads", fetcher.calls.Load())
+	}
+
+	poller.MaxAge = 0
+	_, err := poller.Latest(context.Background(), exchanges.DefaultSymbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetcher.calls.Load() != 2 {
+		t.Errorf("expected the price read again once stale, got %d reads", fetcher.calls.Load())
+	}
+}
+
+func TestPoller_Subscribe(t *testing.T) {
+	fetcher := &fakeFetcher{price: 90000}
+	poller := NewPoller(fetcher, 0)
+
+	prices, cancel := poller.Subscribe(exchanges.DefaultSymbol)
+	_, _ = poller.Latest(context.Background(), exchanges.DefaultSymbol)
+	fetcher.price = 91000
+	_, _ = poller.Latest(context.Background(), exchanges.DefaultSymbol)
+
+	// Only the last price is kept for a slow subscriber
+	price := <-prices
+	if price.Value != 91000 {
+		t.Errorf("expected 91000, got %v", price.Value)
+	}
+
+	cancel()
+	_, _ = poller.Latest(context.Background(), exchanges.DefaultSymbol)
+	select {
+	case price := <-prices:
+		t.Errorf("expected no price once canceled, got %v", price.Value)
+	default:
+	}
+}
+
+func TestTicker_Run(t *testing.T) {
+	fetcher := &fakeFetcher{price: 90000}
+	ticker := NewTicker(&fakeStreamer{prices: []float64{91000}}, fetcher, time.Hour)
+
+	prices, cancel := ticker.Subscribe(exchanges.DefaultSymbol)
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go ticker.Run(ctx, exchanges.DefaultSymbol)
+
+	select {
+	case price := <-prices:
+		if price.Value != 91000 {
+			t.Errorf("expected the streamed price 91000, got %v", price.Value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a streamed price")
+	}
+
+	price, err := ticker.Latest(context.Background(), exchanges.DefaultSymbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.Value != 91000 || fetcher.calls.Load() != 0 {
+		t.Errorf("expected the streamed price without REST read, got %v after %d reads", price.Value, fetcher.calls.Load())
+	}
+}
+
+func TestTicker_FallbackToPolling(t *testing.T) {
+	fetcher := &fakeFetcher{price: 90000}
+	ticker := NewTicker(&fakeStreamer{}, fetcher, time.Hour)
+
+	// Never streamed, the REST ticker is read
+	price, err := ticker.Latest(context.Background(), exchanges.DefaultSymbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.Value != 90000 || fetcher.calls.Load() != 1 {
+		t.Errorf("expected the polled price 90000, got %v after %d reads", price.Value, fetcher.calls.Load())
+	}
+}
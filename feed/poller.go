@@ -0,0 +1,52 @@
+package feed
+
+import (
+	"context"
+	"github.com/fatih/color"
+	"main/exchanges"
+	"time"
+)
+
+// Poller reads the prices from the REST ticker of an exchange
+type Poller struct {
+	cache
+	fetcher Fetcher
+	MaxAge  time.Duration
+}
+
+func NewPoller(fetcher Fetcher, maxAge time.Duration) *Poller {
+	return &Poller{cache: newCache(), fetcher: fetcher, MaxAge: maxAge}
+}
+
+func (p *Poller) Latest(ctx context.Context, symbol exchanges.Symbol) (Price, error) {
+	if price, ok := p.fresh(symbol, p.MaxAge); ok {
+		return price, nil
+	}
+	return p.fetch(ctx, symbol)
+}
+
+func (p *Poller) fetch(ctx context.Context, symbol exchanges.Symbol) (Price, error) {
+	value, err := p.fetcher.GetLastPrice(ctx, symbol)
+	if err != nil {
+		return Price{}, err
+	}
+	return p.publish(symbol, value), nil
+}
+
+// Run reads the price every MaxAge
+func (p *Poller) Run(ctx context.Context, symbol exchanges.Symbol) {
+	ticker := time.NewTicker(p.MaxAge)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := p.fetch(ctx, symbol)
+			if err != nil && ctx.Err() == nil {
+				color.Yellow("Error polling %s price: %v", symbol, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package feed
+
+import (
+	"context"
+	"github.com/fatih/color"
+	"main/exchanges"
+	"time"
+)
+
+// Streamer pushes the prices of a symbol over a WebSocket. StreamPrices
+// blocks until ctx is done or the connection drops.
+type Streamer interface {
+	StreamPrices(ctx context.Context, symbol exchanges.Symbol, prices chan<- float64) error
+}
+
+// Delay before connecting again after the stream dropped
+var retryDelay = 30 * time.Second
+
+// Ticker reads the prices from the WebSocket ticker of an exchange. While
+// the stream is down, the prices get older than MaxAge and are read from
+// the REST ticker instead.
+type Ticker struct {
+	Poller
+	streamer Streamer
+}
+
+func NewTicker(streamer Streamer, fetcher Fetcher, maxAge time.Duration) *Ticker {
+	return &Ticker{Poller: Poller{cache: newCache(), fetcher: fetcher, MaxAge: maxAge}, streamer: streamer}
+}
+
+// Run streams the price, connecting again each time the stream drops
+func (t *Ticker) Run(ctx context.Context, symbol exchanges.Symbol) {
+	prices := make(chan float64)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case value := <-prices:
+				t.publish(symbol, value)
+			}
+		}
+	}()
+
+	for {
+		err := t.streamer.StreamPrices(ctx, symbol, prices)
+		if ctx.Err() != nil {
+			return
+		}
+		color.Yellow("%s price stream dropped, polling: %v", symbol, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
@@ -5,6 +5,7 @@ go 1.23.4
 require (
 	github.com/buger/jsonparser v1.1.1
 	github.com/fatih/color v1.18.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/joho/godotenv v1.5.1
 	github.com/ostafen/clover v1.2.0
 	modernc.org/sqlite v1.39.0
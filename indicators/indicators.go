@@ -0,0 +1,101 @@
+package indicators
+
+import (
+	"main/exchanges"
+	"math"
+)
+
+// ATR is the average true range of the klines: the mean, over each kline
+// after the first, of the widest of its range and its gaps from the close
+// before. 0 with less than 2 klines.
+func ATR(klines []exchanges.Kline) float64 {
+	if len(klines) < 2 {
+		return 0
+	}
+
+	sum := 0.0
+	for i := 1; i < len(klines); i++ {
+		kline, prevClose := klines[i], klines[i-1].Close
+		sum += math.Max(kline.High-kline.Low, math.Max(math.Abs(kline.High-prevClose), math.Abs(kline.Low-prevClose)))
+	}
+	return sum / float64(len(klines)-1)
+}
+
+// ReturnsStdDev is the standard deviation of the returns from one close to
+// the next, as a fraction of the price. 0 with less than 2 klines.
+func ReturnsStdDev(klines []exchanges.Kline) float64 {
+	if len(klines) < 2 {
+		return 0
+	}
+
+	returns := make([]float64, 0, len(klines)-1)
+	mean := 0.0
+	for i := 1; i < len(klines); i++ {
+		r := klines[i].Close/klines[i-1].Close - 1
+		returns = append(returns, r)
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(variance / float64(len(returns)))
+}
+
+// RSI is the relative strength index of the closes over period, with the
+// gains and losses smoothed like Wilder did. 0 with less than period+1
+// klines.
+func RSI(klines []exchanges.Kline, period int) float64 {
+	if period < 1 || len(klines) < period+1 {
+		return 0
+	}
+
+	gain, loss := 0.0, 0.0
+	for i := 1; i < len(klines); i++ {
+		change := klines[i].Close - klines[i-1].Close
+		up, down := math.Max(change, 0), math.Max(-change, 0)
+		if i <= period {
+			gain += up / float64(period)
+			loss += down / float64(period)
+			continue
+		}
+		gain = (gain*float64(period-1) + up) / float64(period)
+		loss = (loss*float64(period-1) + down) / float64(period)
+	}
+
+	if loss == 0 {
+		return 100
+	}
+	return 100 - 100/(1+gain/loss)
+}
+
+// SMA is the mean of the last period closes. 0 with less than period
+// klines.
+func SMA(klines []exchanges.Kline, period int) float64 {
+	if period < 1 || len(klines) < period {
+		return 0
+	}
+
+	sum := 0.0
+	for _, kline := range klines[len(klines)-period:] {
+		sum += kline.Close
+	}
+	return sum / float64(period)
+}
+
+// EMA is the exponential moving average of the closes over period, started
+// from the SMA of the first period closes. 0 with less than period klines.
+func EMA(klines []exchanges.Kline, period int) float64 {
+	if period < 1 || len(klines) < period {
+		return 0
+	}
+
+	ema := SMA(klines[:period], period)
+	alpha := 2 / float64(period+1)
+	for _, kline := range klines[period:] {
+		ema += alpha * (kline.Close - ema)
+	}
+	return ema
+}
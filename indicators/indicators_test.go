@@ -0,0 +1,72 @@
+package indicators
+
+import (
+	"main/exchanges"
+	"math"
+	"testing"
+)
+
+var klines = []exchanges.Kline{
+	{High: 105, Low: 95, Close: 100},
+	{High: 112, Low: 104, Close: 110}, // gap up, true range 12 from the close before
+	{High: 111, Low: 98, Close: 99},   // range 13
+	{High: 101, Low: 97, Close: 99},   // range 4
+}
+
+func TestATR(t *testing.T) {
+	if atr := ATR(klines); atr != 29.0/3 {
+		t.Errorf("expected an ATR of %v, got %v", 29.0/3, atr)
+	}
+	if atr := ATR(klines[:1]); atr != 0 {
+		t.Errorf("expected no ATR from one kline, got %v", atr)
+	}
+}
+
+func TestReturnsStdDev(t *testing.T) {
+	// Returns of 10%, -10% and 0%
+	if stdDev := ReturnsStdDev(klines); math.Abs(stdDev-math.Sqrt(0.02/3)) > 1e-12 {
+		t.Errorf("expected a standard deviation of %v, got %v", math.Sqrt(0.02/3), stdDev)
+	}
+	if stdDev := ReturnsStdDev(klines[:1]); stdDev != 0 {
+		t.Errorf("expected no standard deviation from one kline, got %v", stdDev)
+	}
+}
+
+func TestRSI(t *testing.T) {
+	// Gains 10 and losses 11 over the first 2 closes, then smoothed with no
+	// change
+	if rsi := RSI(klines, 2); math.Abs(rsi-100*2.5/5.25) > 1e-9 {
+		t.Errorf("expected a RSI of %v, got %v", 100*2.5/5.25, rsi)
+	}
+	if rsi := RSI(klines[:2], 1); rsi != 100 {
+		t.Errorf("expected a RSI of 100 without loss, got %v", rsi)
+	}
+	if rsi := RSI(klines, 4); rsi != 0 {
+		t.Errorf("expected no RSI without enough klines, got %v", rsi)
+	}
+}
+
+func TestSMA(t *testing.T) {
+	if sma := SMA(klines, 2); sma != 99 {
+		t.Errorf("expected a SMA of 99, got %v", sma)
+	}
+	if sma := SMA(klines, 4); sma != 102 {
+		t.Errorf("expected a SMA of 102, got %v", sma)
+	}
+	if sma := SMA(klines, 5); sma != 0 {
+		t.Errorf("expected no SMA without enough klines, got %v", sma)
+	}
+}
+
+func TestEMA(t *testing.T) {
+	// From the SMA of 105, 2/3 of the way to each close after
+	if ema := EMA(klines, 2); math.Abs(ema-299.0/3) > 1e-9 {
+		t.Errorf("expected an EMA of %v, got %v", 299.0/3, ema)
+	}
+	if ema := EMA(klines, 4); ema != 102 {
+		t.Errorf("expected the EMA of as many klines as the period to be their SMA, got %v", ema)
+	}
+	if ema := EMA(klines, 5); ema != 0 {
+		t.Errorf("expected no EMA without enough klines, got %v", ema)
+	}
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"main/commands"
 	"main/database"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 const version = "v3.1.0"
@@ -37,7 +40,6 @@ func initialize() error {
 		if err != nil {
 			return err
 		}
-		return nil
 	}
 
 	err := database.InitDatabase()
@@ -60,28 +62,36 @@ func main() {
 		return
 	}
 
+	// CTRL + C cancels the requests in flight
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	cmd := args[0]
 	switch cmd {
 	case "--new", "-n":
-		err := commands.New()
+		err := commands.New(ctx)
 		if err != nil {
 			log.Fatal(err)
 		}
 		break
 	case "--update", "-u":
-		err := commands.Update()
+		err := commands.RecoverPendingCycles(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = commands.Update(ctx)
 		if err != nil {
 			log.Fatal(err)
 		}
 		break
 	case "--server", "-s":
-		err := commands.Server()
+		err := commands.Server(ctx)
 		if err != nil {
 			log.Fatal(err)
 		}
 		break
 	case "--cancel", "-c":
-		err := commands.Cancel()
+		err := commands.Cancel(ctx)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -92,7 +102,7 @@ func main() {
 			log.Fatal(err)
 		}
 	case "--auto", "-a":
-		commands.Auto()
+		commands.Auto(ctx)
 		break
 	case "--export", "-e":
 		commands.Export()
@@ -0,0 +1,40 @@
+package strategy
+
+import (
+	"context"
+	"main/database"
+)
+
+// DefaultName is the strategy of bot.conf until STRATEGY is set
+const DefaultName = "offset"
+
+func init() {
+	Register(DefaultName, func() Strategy { return offsetStrategy{} })
+}
+
+// offsetStrategy buys and sells at the offsets of the market from the
+// price, for its percent of the free balance. It leaves the open cycles
+// alone.
+type offsetStrategy struct{}
+
+func (offsetStrategy) Entry(ctx context.Context, market Market, open []database.Cycle) (*Entry, error) {
+	offsets := market.Offsets
+	entry := &Entry{
+		BuyPrice:       market.Filters.RoundPrice(database.ApplyOffset(market.Price, offsets.Buy, offsets.BuyType)),
+		SellPrice:      market.Filters.RoundPrice(database.ApplyOffset(market.Price, offsets.Sell, offsets.SellType)),
+		BuyOffset:      offsets.Buy,
+		BuyOffsetType:  offsets.BuyType,
+		SellOffset:     offsets.Sell,
+		SellOffsetType: offsets.SellType,
+		Percent:        market.Percent,
+		Volatility:     offsets.Volatility,
+		BuyRule:        offsets.BuyRule,
+		SellRule:       offsets.SellRule,
+	}
+	entry.Quantity = market.Balance * market.Percent / 100 / entry.BuyPrice
+	return entry, nil
+}
+
+func (offsetStrategy) Manage(ctx context.Context, market Market, open []database.Cycle) ([]Action, error) {
+	return nil, nil
+}
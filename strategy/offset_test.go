@@ -0,0 +1,41 @@
+package strategy
+
+import (
+	"context"
+	"main/database"
+	"main/exchanges"
+	"testing"
+)
+
+func TestOffsetEntry(t *testing.T) {
+	s, err := Get(DefaultName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	market := Market{
+		Symbol:  exchanges.DefaultSymbol,
+		Price:   100000,
+		Filters: exchanges.SymbolFilters{TickSize: 0.01},
+		Balance: 990,
+		Percent: 10,
+		Offsets: Offsets{
+			Buy:      -1000,
+			BuyType:  database.OffsetAbsolute,
+			Sell:     1,
+			SellType: database.OffsetPercent,
+			BuyRule:  "-1000",
+			SellRule: "1%",
+		},
+	}
+	entry, err := s.Entry(context.Background(), market, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.BuyPrice != 99000 || entry.SellPrice != 101000 || entry.Quantity != 0.001 {
+		t.Errorf("expected a buy of 0.001 at 99000 selling at 101000, got %+v", entry)
+	}
+	if entry.Percent != 10 || entry.BuyRule != "-1000" || entry.SellRule != "1%" {
+		t.Errorf("expected 10%% with the rules -1000 and 1%%, got %+v", entry)
+	}
+}
@@ -0,0 +1,144 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/database"
+	"main/exchanges"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KlineReader gives the recent klines of a symbol, implemented by the
+// exchange clients
+type KlineReader interface {
+	GetKlines(ctx context.Context, symbol exchanges.Symbol, interval time.Duration, limit int) ([]exchanges.Kline, error)
+}
+
+// Market is what a strategy knows when it decides
+type Market struct {
+	Exchange string
+	Symbol   exchanges.Symbol
+	Price    float64 // last price
+	Filters  exchanges.SymbolFilters
+	Klines   KlineReader
+
+	// Known when deciding an entry, zero when managing the open cycles
+	Balance float64 // free balance in the quote asset
+	Percent float64 // of the free balance, PERCENT
+	Offsets Offsets
+}
+
+// Offsets are the offsets of bot.conf from the price, BUY_OFFSET and
+// SELL_OFFSET or multiples of the volatility with VOLATILITY
+type Offsets struct {
+	Buy        float64
+	BuyType    database.OffsetType
+	Sell       float64
+	SellType   database.OffsetType
+	Volatility float64 // measured, 0 for fixed offsets
+
+	// How the offsets were set, shown with the new cycle
+	BuyRule  string
+	SellRule string
+}
+
+// Entry is a new cycle a strategy wants to open. Prices and quantity are
+// rounded to the filters of the symbol before the order is placed.
+type Entry struct {
+	BuyPrice  float64
+	SellPrice float64 // target
+	Quantity  float64 // base asset to buy
+
+	// Recorded on the cycle, optional
+	BuyOffset      float64
+	BuyOffsetType  database.OffsetType
+	SellOffset     float64
+	SellOffsetType database.OffsetType
+	Percent        float64 // of the free balance
+	Volatility     float64
+
+	// How the prices were set, shown with the new cycle
+	BuyRule  string
+	SellRule string
+}
+
+// ActionKind is what a strategy does with an open cycle
+type ActionKind string
+
+const (
+	// Reprice moves the order of the cycle to Price: the buy order until
+	// something is bought, then the sell order or the trailing threshold
+	// until something is sold
+	Reprice ActionKind = "reprice"
+	// Cancel cancels the buy order of a cycle that bought nothing
+	Cancel ActionKind = "cancel"
+	// Exit sells what a cycle bought at once, or cancels its buy order when
+	// it bought nothing
+	Exit ActionKind = "exit"
+)
+
+// Action is a decision on an open cycle
+type Action struct {
+	CycleId int
+	Kind    ActionKind
+	Price   float64 // new price of a Reprice
+	Reason  string
+}
+
+// Strategy decides the entries and manages the open cycles of a symbol.
+// Strategies register under a name, STRATEGY in bot.conf chooses the one
+// the bot runs.
+type Strategy interface {
+	// Entry returns the cycle to open now, nil to wait
+	Entry(ctx context.Context, market Market, open []database.Cycle) (*Entry, error)
+	// Manage returns the actions on the open cycles, once per update
+	Manage(ctx context.Context, market Market, open []database.Cycle) ([]Action, error)
+}
+
+// ErrUnknown is returned by Get for a name nothing registered
+var ErrUnknown = errors.New("unknown strategy")
+
+var (
+	registry   = map[string]func() Strategy{}
+	registryMu sync.Mutex
+)
+
+// Register makes a strategy available under name. It panics when the name
+// is taken, like registering twice from init would be a bug.
+func Register(name string, factory func() Strategy) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic("strategy " + name + " registered twice")
+	}
+	registry[name] = factory
+}
+
+// Get returns a new instance of the strategy registered under name
+func Get(name string) (Strategy, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+	return factory(), nil
+}
+
+// Names returns the registered strategies, sorted
+func Names() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
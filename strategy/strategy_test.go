@@ -0,0 +1,46 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"main/database"
+	"slices"
+	"testing"
+)
+
+type holdStrategy struct{}
+
+func (holdStrategy) Entry(ctx context.Context, market Market, open []database.Cycle) (*Entry, error) {
+	return nil, nil
+}
+
+func (holdStrategy) Manage(ctx context.Context, market Market, open []database.Cycle) ([]Action, error) {
+	return nil, nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register("hold", func() Strategy { return holdStrategy{} })
+
+	s, err := Get("hold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(holdStrategy); !ok {
+		t.Errorf("expected the hold strategy, got %T", s)
+	}
+	if !slices.Contains(Names(), "hold") {
+		t.Errorf("expected hold in %v", Names())
+	}
+
+	_, err = Get("missing")
+	if !errors.Is(err, ErrUnknown) {
+		t.Errorf("expected ErrUnknown, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic registering hold twice")
+		}
+	}()
+	Register("hold", func() Strategy { return holdStrategy{} })
+}
@@ -65,6 +65,8 @@ func FromCloverToSqlite() {
 		cycle.Buy.Price = buyPrice
 		cycle.Sell.Price = sellPrice
 		cycle.Exchange = exchange
+		// The old bot only traded BTC/USDC
+		cycle.Symbol = "BTC/USDC"
 		cycle.Buy.ID = oldCycle.BuyID
 		cycle.Sell.ID = oldCycle.SellID
 